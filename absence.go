@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"mcp-server/database"
+	"mcp-server/dates"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	AbsenceKindFullDay   = "full_day"    // Бүтэн өдөр
+	AbsenceKindHalfDayAM = "half_day_am" // Өглөөний хагас өдөр
+	AbsenceKindHalfDayPM = "half_day_pm" // Үдээс хойших хагас өдөр
+	AbsenceKindHourly    = "hourly"      // Цагаар
+)
+
+var AbsenceKinds = []string{AbsenceKindFullDay, AbsenceKindHalfDayAM, AbsenceKindHalfDayPM, AbsenceKindHourly}
+
+// AbsencePeriod чөлөөний хугацаа, ажлын хуанлиас тооцсон цаг
+type AbsencePeriod struct {
+	Kind  string
+	Start time.Time
+	End   time.Time
+	Hours float64
+}
+
+// NewAbsencePeriod validates the requested kind against the working calendar
+// and computes the absence boundaries and working hours it covers. An empty
+// kind is inferred from the input ("tomorrow afternoon" -> half_day_pm).
+func NewAbsencePeriod(kind string, start, end dates.Resolved) (AbsencePeriod, error) {
+	cal := dates.WorkingCalendar()
+	if kind == "" {
+		kind = inferAbsenceKind(start, end)
+	}
+
+	period := AbsencePeriod{Kind: kind}
+	switch kind {
+	case AbsenceKindFullDay:
+		period.Start = cal.At(start.Time, cal.WorkStart)
+		period.End = cal.At(end.Time, cal.WorkEnd)
+
+	case AbsenceKindHalfDayAM, AbsenceKindHalfDayPM:
+		if !dates.SameDay(start.Time, end.Time) {
+			return period, fmt.Errorf("%s absence must start and end on the same day", kind)
+		}
+		if kind == AbsenceKindHalfDayAM {
+			period.Start = cal.At(start.Time, cal.WorkStart)
+			period.End = cal.At(start.Time, cal.LunchStart)
+		} else {
+			period.Start = cal.At(start.Time, cal.LunchEnd)
+			period.End = cal.At(start.Time, cal.WorkEnd)
+		}
+
+	case AbsenceKindHourly:
+		if !start.HasTime || !end.HasTime {
+			return period, fmt.Errorf("hourly absence needs start_date and end_date with a time of day")
+		}
+		if !dates.SameDay(start.Time, end.Time) {
+			return period, fmt.Errorf("hourly absence must start and end on the same day")
+		}
+		if !end.Time.After(start.Time) {
+			return period, fmt.Errorf("hourly absence must end after it starts")
+		}
+		if !cal.WithinWorkingDay(start.Time, end.Time) {
+			return period, fmt.Errorf("hourly absence must be within working hours %s-%s",
+				cal.At(start.Time, cal.WorkStart).Format("15:04"), cal.At(start.Time, cal.WorkEnd).Format("15:04"))
+		}
+		period.Start, period.End = start.Time, end.Time
+
+	default:
+		return period, fmt.Errorf("unknown absence kind %q", kind)
+	}
+
+	if kind != AbsenceKindFullDay && !cal.IsWorkingDay(period.Start) {
+		return period, fmt.Errorf("%s is not a working day", dates.FormatDate(period.Start))
+	}
+
+	period.Hours = cal.WorkingHours(period.Start, period.End)
+	if period.Hours <= 0 {
+		return period, fmt.Errorf("the requested period has no working hours")
+	}
+	return period, nil
+}
+
+func inferAbsenceKind(start, end dates.Resolved) string {
+	switch {
+	case start.Part == dates.PartMorning && dates.SameDay(start.Time, end.Time):
+		return AbsenceKindHalfDayAM
+	case start.Part == dates.PartAfternoon && dates.SameDay(start.Time, end.Time):
+		return AbsenceKindHalfDayPM
+	case start.HasTime && end.HasTime && dates.SameDay(start.Time, end.Time) && end.Time.After(start.Time):
+		return AbsenceKindHourly
+	}
+	return AbsenceKindFullDay
+}
+
+// AbsenceSummary list_absences-ийн мөр
+type AbsenceSummary struct {
+	ID            uint      `json:"id"`
+	EmployeeID    uint      `json:"employee_id"`
+	EmployeeName  string    `json:"employee_name"`
+	EmployeeEmail string    `json:"employee_email"`
+	LeaderID      uint      `json:"leader_id"`
+	Kind          string    `json:"kind"`
+	Reason        string    `json:"reason"`
+	Status        string    `json:"status"`
+	StartDate     time.Time `json:"start_date"`
+	EndDate       time.Time `json:"end_date"`
+	InActiveHours float64   `json:"in_active_hours"`
+	Description   string    `json:"description"`
+}
+
+func listAbsences(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	query := caller.ScopeAbsences(database.DB.Model(&database.Absence{}))
+	if status := stringArg(args, "status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if id, ok := floatArg(args, "employee_id"); ok {
+		query = query.Where("employee_id = ?", uint(id))
+	}
+	if value := stringArg(args, "from"); value != "" {
+		from, err := dates.Parse(value)
+		if err != nil {
+			return nil, NewToolError(http.StatusBadRequest, "Invalid from: %s", err)
+		}
+		query = query.Where("COALESCE(end_date, start_date) >= ?", dates.StartOfDay(from.Time))
+	}
+	if value := stringArg(args, "to"); value != "" {
+		to, err := dates.Parse(value)
+		if err != nil {
+			return nil, NewToolError(http.StatusBadRequest, "Invalid to: %s", err)
+		}
+		query = query.Where("start_date < ?", dates.EndOfDay(to.Time))
+	}
+
+	var absences []database.Absence
+	if err := query.Preload("Employee").Order("start_date DESC").Limit(200).Find(&absences).Error; err != nil {
+		return nil, err
+	}
+	summaries := make([]AbsenceSummary, 0, len(absences))
+	for _, a := range absences {
+		summary := AbsenceSummary{
+			ID:            a.ID,
+			EmployeeID:    a.EmployeeID,
+			LeaderID:      a.LeaderID,
+			Kind:          a.Kind,
+			Reason:        a.Reason,
+			Status:        a.Status,
+			StartDate:     a.StartDate,
+			EndDate:       a.EndDate,
+			InActiveHours: a.InActiveHours,
+			Description:   a.Description,
+		}
+		if a.Employee != nil {
+			summary.EmployeeName = strings.TrimSpace(a.Employee.FirstName + " " + a.Employee.LastName)
+			summary.EmployeeEmail = a.Employee.Email
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// AbsenceReportRow ажилтан, шалтгаан бүрийн нийлбэр
+type AbsenceReportRow struct {
+	EmployeeID    uint    `json:"employee_id"`
+	EmployeeName  string  `json:"employee_name"`
+	Reason        string  `json:"reason"`
+	Count         int     `json:"count"`
+	InActiveHours float64 `json:"in_active_hours"`
+	Days          float64 `json:"days"`
+}
+
+func getAbsenceReport(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	today := dates.StartOfDay(dates.Now())
+	from := today.AddDate(0, 0, 1-today.Day())
+	to := from.AddDate(0, 1, 0)
+	if value := stringArg(args, "from"); value != "" {
+		resolved, err := dates.Parse(value)
+		if err != nil {
+			return nil, NewToolError(http.StatusBadRequest, "Invalid from: %s", err)
+		}
+		from = dates.StartOfDay(resolved.Time)
+	}
+	if value := stringArg(args, "to"); value != "" {
+		resolved, err := dates.Parse(value)
+		if err != nil {
+			return nil, NewToolError(http.StatusBadRequest, "Invalid to: %s", err)
+		}
+		// Хоёр салбарт to нь дараагийн өдрийн эхлэл, хязгаарт орохгүй
+		to = dates.StartOfDay(resolved.Time).AddDate(0, 0, 1)
+	}
+	status := stringArg(args, "status")
+	if status == "" {
+		status = "approved"
+	}
+
+	var rows []AbsenceReportRow
+	query := caller.ScopeAbsences(database.DB.Model(&database.Absence{})).
+		Select("employee_id, reason, COUNT(*) AS count, SUM(in_active_hours) AS in_active_hours").
+		Where("status = ? AND start_date >= ? AND start_date < ?", status, from, to).
+		Group("employee_id, reason").Order("employee_id, reason")
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, row := range rows {
+		ids = append(ids, row.EmployeeID)
+	}
+	var users []database.User
+	database.DB.Select("id, first_name, last_name").Where("id IN ?", append(ids, 0)).Find(&users)
+	names := map[uint]string{}
+	for _, u := range users {
+		names[u.ID] = strings.TrimSpace(u.FirstName + " " + u.LastName)
+	}
+	dayHours := dates.WorkingCalendar().DayHours()
+	for i := range rows {
+		rows[i].EmployeeName = names[rows[i].EmployeeID]
+		rows[i].Days = rows[i].InActiveHours / dayHours
+	}
+
+	return map[string]interface{}{
+		"from":   dates.FormatDate(from),
+		"to":     dates.FormatDate(to.AddDate(0, 0, -1)),
+		"status": status,
+		"rows":   rows,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"mcp-server/dates"
+	"testing"
+	"time"
+)
+
+// absenceCalendar uses Asia/Ulaanbaatar, 09:00-18:00 with lunch 13:00-14:00,
+// Sat/Sun off and holidays on 03-08 every year and on 2024-03-12
+func absenceCalendar(t *testing.T) (now time.Time, loc *time.Location) {
+	t.Helper()
+	if err := dates.Configure("Asia/Ulaanbaatar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dates.ConfigureCalendar("09:00", "13:00", "14:00", "18:00", "saturday,sunday", "03-08,2024-03-12"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dates.ConfigureCalendar("", "", "", "", "", "") })
+	loc = dates.Location()
+	// Лхагва 2024-03-06 10:30
+	return time.Date(2024, 3, 6, 10, 30, 0, 0, loc), loc
+}
+
+func TestNewAbsencePeriod(t *testing.T) {
+	now, loc := absenceCalendar(t)
+	resolve := func(input string) dates.Resolved {
+		r, err := dates.Resolve(input, now, loc)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", input, err)
+		}
+		return r
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, loc)
+	}
+	tests := []struct {
+		name       string
+		kind       string
+		start, end string
+		wantKind   string
+		from, to   time.Time
+		hours      float64
+	}{
+		{"one day", "", "2024-03-04", "2024-03-04", AbsenceKindFullDay, at(4, 9, 0), at(4, 18, 0), 8},
+		{"three days", "", "2024-03-04", "2024-03-06", AbsenceKindFullDay, at(4, 9, 0), at(6, 18, 0), 24},
+		{"over a weekend", AbsenceKindFullDay, "2024-03-01", "2024-03-04", AbsenceKindFullDay, at(1, 9, 0), at(4, 18, 0), 16},
+		{"over a yearly holiday and weekend", "", "2024-03-07", "2024-03-11", AbsenceKindFullDay, at(7, 9, 0), at(11, 18, 0), 16},
+		{"over a dated holiday", "", "2024-03-11", "2024-03-13", AbsenceKindFullDay, at(11, 9, 0), at(13, 18, 0), 16},
+		{"starting on a weekend", "", "2024-03-09", "2024-03-11", AbsenceKindFullDay, at(9, 9, 0), at(11, 18, 0), 8},
+		{"morning inferred", "", "tomorrow morning", "tomorrow morning", AbsenceKindHalfDayAM, at(7, 9, 0), at(7, 13, 0), 4},
+		{"afternoon inferred", "", "маргааш үдээс хойш", "маргааш", AbsenceKindHalfDayPM, at(7, 14, 0), at(7, 18, 0), 4},
+		{"afternoon to another day is full days", "", "tomorrow afternoon", "next monday", AbsenceKindFullDay, at(7, 9, 0), at(11, 18, 0), 16},
+		{"half day am", AbsenceKindHalfDayAM, "2024-03-04", "2024-03-04", AbsenceKindHalfDayAM, at(4, 9, 0), at(4, 13, 0), 4},
+		{"half day pm", AbsenceKindHalfDayPM, "2024-03-04 15:00", "2024-03-04", AbsenceKindHalfDayPM, at(4, 14, 0), at(4, 18, 0), 4},
+		{"hourly inferred", "", "2024-03-04 10:00", "2024-03-04 12:30", AbsenceKindHourly, at(4, 10, 0), at(4, 12, 30), 2.5},
+		{"hourly across lunch", AbsenceKindHourly, "2024-03-04 12:00", "2024-03-04 15:00", AbsenceKindHourly, at(4, 12, 0), at(4, 15, 0), 2},
+		{"date-only end is a full day", "", "2024-03-04 10:00", "2024-03-05", AbsenceKindFullDay, at(4, 9, 0), at(5, 18, 0), 16},
+	}
+	for _, tt := range tests {
+		period, err := NewAbsencePeriod(tt.kind, resolve(tt.start), resolve(tt.end))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if period.Kind != tt.wantKind || !period.Start.Equal(tt.from) || !period.End.Equal(tt.to) || period.Hours != tt.hours {
+			t.Errorf("%s: got %s %s - %s (%vh), want %s %s - %s (%vh)", tt.name,
+				period.Kind, period.Start, period.End, period.Hours, tt.wantKind, tt.from, tt.to, tt.hours)
+		}
+	}
+}
+
+func TestNewAbsencePeriodRejects(t *testing.T) {
+	now, loc := absenceCalendar(t)
+	tests := []struct {
+		name       string
+		kind       string
+		start, end string
+	}{
+		{"weekend only", AbsenceKindFullDay, "2024-03-09", "2024-03-10"},
+		{"holiday only", "", "2024-03-08", "2024-03-08"},
+		{"half day over two days", AbsenceKindHalfDayAM, "2024-03-04", "2024-03-05"},
+		{"half day on a Saturday", AbsenceKindHalfDayPM, "2024-03-09", "2024-03-09"},
+		{"half day on a holiday", AbsenceKindHalfDayAM, "2024-03-08", "2024-03-08"},
+		{"hourly without time", AbsenceKindHourly, "2024-03-04", "2024-03-04"},
+		{"hourly over two days", AbsenceKindHourly, "2024-03-04 10:00", "2024-03-05 11:00"},
+		{"hourly ends before start", AbsenceKindHourly, "2024-03-04 12:00", "2024-03-04 10:00"},
+		{"hourly before work", AbsenceKindHourly, "2024-03-04 07:00", "2024-03-04 08:30"},
+		{"hourly after work", AbsenceKindHourly, "2024-03-04 17:00", "2024-03-04 19:00"},
+		{"hourly on a Sunday", "", "2024-03-10 10:00", "2024-03-10 12:00"},
+		{"hourly inside lunch", "", "2024-03-04 13:00", "2024-03-04 14:00"},
+		{"unknown kind", "weekly", "2024-03-04", "2024-03-04"},
+	}
+	for _, tt := range tests {
+		start, err := dates.Resolve(tt.start, now, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		end, err := dates.Resolve(tt.end, now, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if period, err := NewAbsencePeriod(tt.kind, start, end); err == nil {
+			t.Errorf("%s: got %s %s - %s (%vh), want an error", tt.name, period.Kind, period.Start, period.End, period.Hours)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"mcp-server/auth"
+	"mcp-server/database"
+	"mcp-server/dates"
+	"mcp-server/secrets"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const defaultActionLinkTTL = 72 * time.Hour
+
+var errInvalidActionLink = NewToolError(http.StatusNotFound, "This link is invalid or has expired")
+
+// actionLinkTTL is how long the approve/reject links stay valid, ACTION_LINK_TTL
+func actionLinkTTL() time.Duration {
+	if ttl := viper.GetDuration("ACTION_LINK_TTL"); ttl > 0 {
+		return ttl
+	}
+	return defaultActionLinkTTL
+}
+
+// actionSigningKey нь холбоосыг гарын үсэглэнэ, ACTION_LINK_KEY эсвэл
+// CIPHER_KEY-ээс гаргасан тусдаа түлхүүр
+func actionSigningKey() []byte {
+	return secrets.SigningKey("ACTION_LINK_KEY", "mcp-server action link")
+}
+
+func actionSignature(token string, record *database.ActionToken) string {
+	mac := hmac.New(sha256.New, actionSigningKey())
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%d\n%d", token, record.Action, record.AbsenceID, record.UserID, record.ExpiresAt.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func hashActionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueActionLink stores a single-use token for action on absence and returns
+// the link. The action token keeps only the hash; the link itself sits in the
+// request mail's outbox payload until it is sent, when the payload is
+// cleared. The signature binds the token to the absence, the leader and the
+// expiry.
+func issueActionLink(tx *gorm.DB, absence *database.Absence, leaderID uint, action string) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	record := &database.ActionToken{
+		Action:    action,
+		AbsenceID: absence.ID,
+		UserID:    leaderID,
+		TokenHash: hashActionToken(token),
+		ExpiresAt: time.Now().Add(actionLinkTTL()).Truncate(time.Second),
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return "", err
+	}
+	return PublicURL("/absence-actions/" + token + "." + actionSignature(token, record)), nil
+}
+
+// revokeActionLinks marks every unused link of the absence as used, called
+// whenever the absence leaves pending
+func revokeActionLinks(tx *gorm.DB, absenceID uint, now time.Time) error {
+	return tx.Model(&database.ActionToken{}).
+		Where("absence_id = ? AND used_at IS NULL", absenceID).
+		Update("used_at", now).Error
+}
+
+// lookupActionLink verifies the signature, expiry and single use of a link
+func lookupActionLink(value string) (*database.ActionToken, error) {
+	token, sig, ok := strings.Cut(value, ".")
+	if !ok || token == "" {
+		return nil, errInvalidActionLink
+	}
+	var record database.ActionToken
+	if err := database.DB.Where("token_hash = ?", hashActionToken(token)).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidActionLink
+		}
+		return nil, err
+	}
+	if !hmac.Equal([]byte(actionSignature(token, &record)), []byte(sig)) || time.Now().After(record.ExpiresAt) {
+		return nil, errInvalidActionLink
+	}
+	if record.UsedAt != nil {
+		return nil, NewToolError(http.StatusGone, "This link was already used or the request was already decided")
+	}
+	return &record, nil
+}
+
+// actionCaller acts as the leader the link was sent to. The leader must still
+// be active and the role must still allow the matching tool, as auth does for
+// every other login, so a deactivated leader cannot use old links.
+func actionCaller(record *database.ActionToken) (*Caller, error) {
+	var leader database.User
+	if err := database.DB.First(&leader, record.UserID).Error; err != nil {
+		return nil, errInvalidActionLink
+	}
+	if !leader.IsActive {
+		return nil, NewToolError(http.StatusForbidden, "Your account is not active")
+	}
+	caller, err := NewCaller(&auth.Identity{
+		User:   &leader,
+		Method: auth.MethodAction,
+		Scopes: []string{auth.ScopeAbsenceApprove},
+	})
+	if err != nil {
+		return nil, err
+	}
+	tool := "approve_absence"
+	if record.Action == AbsenceStatusRejected {
+		tool = "reject_absence"
+	}
+	if !caller.CanUse(tool) {
+		return nil, NewToolError(http.StatusForbidden, "Your role is no longer allowed to decide absences")
+	}
+	return caller, nil
+}
+
+var actionPage = template.Must(template.New("action").Funcs(template.FuncMap{"datetime": dates.FormatDateTime}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <style>
+    body { font-family: sans-serif; margin: 24px; max-width: 560px; }
+    textarea { width: 100%; height: 96px; }
+    button { padding: 8px 20px; font-size: 16px; }
+    .approved { color: #1b7a2f; }
+    .rejected { color: #b00020; }
+  </style>
+</head>
+<body>
+  <h2 class="{{.Action}}">{{.Title}}</h2>
+  {{if .Message}}<p>{{.Message}}</p>{{end}}
+  {{with .Absence}}
+  <ul>
+    <li><strong>{{$.Labels.Employee}}:</strong> {{$.Employee}}</li>
+    <li><strong>{{$.Labels.Start}}:</strong> {{datetime .StartDate}}</li>
+    <li><strong>{{$.Labels.End}}:</strong> {{datetime .EndDate}}</li>
+    <li><strong>{{$.Labels.Reason}}:</strong> {{$.Reason}}</li>
+    {{if .Description}}<li><strong>{{$.Labels.Description}}:</strong> {{.Description}}</li>{{end}}
+  </ul>
+  {{end}}
+  {{if .Form}}
+  <form method="post">
+    <p><label for="comment">{{.Labels.Comment}}</label></p>
+    <textarea id="comment" name="comment" maxlength="1000"></textarea>
+    <p><button type="submit">{{.Labels.Confirm}}</button></p>
+  </form>
+  {{end}}
+</body>
+</html>
+`))
+
+// actionLabels нь баталгаажуулах хуудасны бичвэрүүд, удирдагчийн хэлээр
+type actionLabels struct {
+	Approve, Reject, Approved, Rejected, Invalid string
+	Employee, Start, End, Reason, Description    string
+	Comment, Confirm                             string
+}
+
+var actionPageLabels = map[string]actionLabels{
+	"en": {
+		Approve: "Approve absence request?", Reject: "Reject absence request?",
+		Approved: "Absence approved", Rejected: "Absence rejected", Invalid: "Link cannot be used",
+		Employee: "Employee", Start: "Start Date", End: "End Date", Reason: "Reason", Description: "Description",
+		Comment: "Comment for the employee (optional)", Confirm: "Confirm",
+	},
+	"mn": {
+		Approve: "Чөлөөний хүсэлтийг зөвшөөрөх үү?", Reject: "Чөлөөний хүсэлтийг татгалзах уу?",
+		Approved: "Чөлөө зөвшөөрөгдлөө", Rejected: "Чөлөө татгалзагдлаа", Invalid: "Холбоосыг ашиглах боломжгүй",
+		Employee: "Ажилтан", Start: "Эхлэх", End: "Дуусах", Reason: "Шалтгаан", Description: "Тайлбар",
+		Comment: "Ажилтанд өгөх тайлбар (заавал биш)", Confirm: "Батлах",
+	},
+}
+
+// pageLanguage returns the user's language when the page has labels for it
+func pageLanguage(user *database.User) string {
+	lang := recipientLanguage(database.DB, user)
+	if lang == "" {
+		lang = viper.GetString("MAIL_DEFAULT_LANGUAGE")
+	}
+	if _, ok := actionPageLabels[lang]; ok {
+		return lang
+	}
+	return "mn"
+}
+
+func labelsFor(user *database.User) actionLabels {
+	return actionPageLabels[pageLanguage(user)]
+}
+
+type actionPageData struct {
+	Title    string
+	Message  string
+	Action   string
+	Form     bool
+	Labels   actionLabels
+	Absence  *database.Absence
+	Employee string
+	Reason   string
+}
+
+func renderActionPage(w http.ResponseWriter, status int, data actionPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := actionPage.Execute(w, data); err != nil {
+		fmt.Println("Failed to render action page", err)
+	}
+}
+
+func renderActionError(w http.ResponseWriter, labels actionLabels, err error) {
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) {
+		fmt.Println("Action link error", err)
+		toolErr = NewToolError(http.StatusInternalServerError, "Internal server error")
+	}
+	renderActionPage(w, toolErr.Status, actionPageData{Title: labels.Invalid, Message: toolErr.Message, Labels: labels})
+}
+
+// AbsenceActionHandler serves the links in the request email.
+// GET /absence-actions/{token} shows the request with a comment box, POST
+// decides it as the leader through DecideAbsence. GET never changes state
+// so mail scanners that follow links cannot approve anything.
+func AbsenceActionHandler(w http.ResponseWriter, r *http.Request) {
+	labels := actionPageLabels["mn"]
+	record, err := lookupActionLink(r.PathValue("token"))
+	if err != nil {
+		renderActionError(w, labels, err)
+		return
+	}
+	caller, err := actionCaller(record)
+	if err != nil {
+		renderActionError(w, labels, err)
+		return
+	}
+	labels = labelsFor(caller.User)
+
+	var absence database.Absence
+	if err := database.DB.Preload("Employee").First(&absence, record.AbsenceID).Error; err != nil {
+		renderActionError(w, labels, errInvalidActionLink)
+		return
+	}
+	page := actionPageData{
+		Action:   record.Action,
+		Labels:   labels,
+		Absence:  &absence,
+		Employee: fullName(absence.Employee),
+		Reason:   reasonName(absence.Reason, pageLanguage(caller.User)),
+	}
+
+	if r.Method != http.MethodPost {
+		page.Title, page.Form = labels.Approve, true
+		if record.Action == AbsenceStatusRejected {
+			page.Title = labels.Reject
+		}
+		renderActionPage(w, http.StatusOK, page)
+		return
+	}
+
+	comment := strings.TrimSpace(r.FormValue("comment"))
+	if len([]rune(comment)) > 1000 {
+		renderActionError(w, labels, NewToolError(http.StatusBadRequest, "Comment is too long"))
+		return
+	}
+	decided, err := DecideAbsence(caller, absence.ID, record.Action, comment)
+	if err != nil {
+		renderActionError(w, labels, err)
+		return
+	}
+	page.Absence = decided
+	page.Title = labels.Approved
+	if record.Action == AbsenceStatusRejected {
+		page.Title = labels.Rejected
+	}
+	if comment != "" {
+		page.Message = comment
+	}
+	renderActionPage(w, http.StatusOK, page)
+}
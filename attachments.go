@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mcp-server/auth"
+	"mcp-server/database"
+	"mcp-server/storage"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAttachmentMaxSize = 10 << 20
+	signedURLExpiry          = 15 * time.Minute
+)
+
+// Хавсралтад зөвшөөрөгдөх төрөл -> өргөтгөл
+var attachmentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+}
+
+func attachmentMaxSize() int64 {
+	if size := viper.GetInt64("ATTACHMENT_MAX_SIZE"); size > 0 {
+		return size
+	}
+	return defaultAttachmentMaxSize
+}
+
+// findAbsence loads the absence if it is visible to the caller
+func findAbsence(caller *Caller, id uint) (*database.Absence, error) {
+	var absence database.Absence
+	if err := database.DB.First(&absence, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "Absence not found")
+		}
+		return nil, err
+	}
+	if !caller.CanAccessAbsence(&absence) {
+		return nil, NewToolError(http.StatusForbidden, "Absence belongs to another user")
+	}
+	return &absence, nil
+}
+
+// SaveAbsenceAttachment checks size and type, stores the content in the
+// configured storage, creates the File row and links it to the absence.
+func SaveAbsenceAttachment(caller *Caller, absence *database.Absence, originalName string, content io.Reader) (*database.File, error) {
+	maxSize := attachmentMaxSize()
+	data, err := io.ReadAll(io.LimitReader(content, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, NewToolError(http.StatusBadRequest, "attachment is empty")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, NewToolError(http.StatusRequestEntityTooLarge, "attachment exceeds %d bytes", maxSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	ext, ok := attachmentTypes[contentType]
+	if !ok {
+		return nil, NewToolError(http.StatusUnsupportedMediaType, "attachment type %s is not allowed", contentType)
+	}
+
+	object, err := storage.Save(context.Background(), data, ext, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	createdUserID := caller.ID
+	file := database.File{
+		OriginalName:  filepath.Base(originalName),
+		FileName:      object.Name,
+		Extention:     ext,
+		PhysicalPath:  object.Key,
+		FileSize:      object.Size,
+		CreatedUserID: &createdUserID,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&file).Error; err != nil {
+			return err
+		}
+		return tx.Model(absence).Association("Attachments").Append(&file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Attachment saved", absence.ID, file.ID, file.FileSize)
+	return &file, nil
+}
+
+// findAbsenceAttachment returns the file only when it belongs to the absence
+func findAbsenceAttachment(absenceID, fileID uint) (*database.File, error) {
+	var file database.File
+	err := database.DB.Joins("JOIN pmt_absence_attachments aa ON aa.file_id = pmt_files.id").
+		Where("aa.absence_id = ? AND pmt_files.id = ?", absenceID, fileID).First(&file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, NewToolError(http.StatusNotFound, "Attachment not found")
+	}
+	return &file, err
+}
+
+func absenceAttachmentCount(absenceID uint) int64 {
+	var count int64
+	database.DB.Table("pmt_absence_attachments").Where("absence_id = ?", absenceID).Count(&count)
+	return count
+}
+
+func fileContentType(file *database.File) string {
+	if contentType := mime.TypeByExtension(file.Extention); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+func uploadAbsenceAttachment(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	absenceID, err := uintArg(args, "absence_id")
+	if err != nil {
+		return nil, err
+	}
+	fileName, err := requiredStringArg(args, "file_name")
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := requiredStringArg(args, "content_base64")
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, NewToolError(http.StatusBadRequest, "content_base64 is not valid base64")
+	}
+
+	absence, err := findAbsence(caller, absenceID)
+	if err != nil {
+		return nil, err
+	}
+	return SaveAbsenceAttachment(caller, absence, fileName, bytes.NewReader(data))
+}
+
+func listAbsenceAttachments(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	absenceID, err := uintArg(args, "absence_id")
+	if err != nil {
+		return nil, err
+	}
+	absence, err := findAbsence(caller, absenceID)
+	if err != nil {
+		return nil, err
+	}
+	var files []database.File
+	if err := database.DB.Model(absence).Association("Attachments").Find(&files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func downloadAbsenceAttachment(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	absenceID, err := uintArg(args, "absence_id")
+	if err != nil {
+		return nil, err
+	}
+	fileID, err := uintArg(args, "file_id")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := findAbsence(caller, absenceID); err != nil {
+		return nil, err
+	}
+	file, err := findAbsenceAttachment(absenceID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := storage.ReadAll(context.Background(), file.PhysicalPath)
+	if err != nil {
+		return nil, err
+	}
+	url, err := storage.SignedURL(context.Background(), file.PhysicalPath, signedURLExpiry)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"file":           file,
+		"content_type":   fileContentType(file),
+		"content_base64": base64.StdEncoding.EncodeToString(data),
+		"download_url":   url,
+	}, nil
+}
+
+// AttachmentUploadHandler POST /absences/{id}/attachments, multipart "file" талбар
+func AttachmentUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !auth.RequireScope(w, r, auth.ScopeAbsenceWrite) {
+		return
+	}
+	absenceID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid absence id", http.StatusBadRequest)
+		return
+	}
+	caller, err := callerFromRequest(r)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+	absence, err := findAbsence(caller, uint(absenceID))
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, attachmentMaxSize()+1<<20)
+	part, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Multipart field \"file\" is required", http.StatusBadRequest)
+		return
+	}
+	defer part.Close()
+
+	file, err := SaveAbsenceAttachment(caller, absence, header.Filename, part)
+	if err != nil {
+		fmt.Println("Failed to save attachment", err)
+		writeToolError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(FunctionResponse{Result: file})
+}
+
+// AttachmentDownloadHandler GET /absences/{id}/attachments/{file_id}
+func AttachmentDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	if !auth.RequireScope(w, r, auth.ScopeAbsenceRead) {
+		return
+	}
+	absenceID, err1 := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	fileID, err2 := strconv.ParseUint(r.PathValue("file_id"), 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+	caller, err := callerFromRequest(r)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+	if _, err := findAbsence(caller, uint(absenceID)); err != nil {
+		writeToolError(w, err)
+		return
+	}
+	file, err := findAbsenceAttachment(uint(absenceID), uint(fileID))
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+	serveStoredFile(w, r, file.PhysicalPath, fileContentType(file), file.OriginalName)
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"mcp-server/database"
+)
+
+// Бүртгэх үйлдлүүд
+const (
+	ActionViewSensitive = "view_sensitive"             // Хувийн мэдээллийг бүтнээр харсан
+	ActionRevealRD      = "reveal_registration_number" // РД-г тайлж харсан
+	ActionSetRole       = "set_user_role"              // Хэрэглэгчийн эрхийг өөрчилсөн
+)
+
+// Record writes an audit entry. Failures are logged and returned; callers
+// that expose sensitive data or change access fail when it fails.
+func Record(actorID uint, action, subjectType string, subjectIDs []uint, detail interface{}) error {
+	ids, _ := json.Marshal(subjectIDs)
+	entry := database.AuditLog{
+		ActorID:     actorID,
+		Action:      action,
+		SubjectType: subjectType,
+		SubjectIDs:  string(ids),
+	}
+	if detail != nil {
+		data, err := json.Marshal(detail)
+		if err != nil {
+			return err
+		}
+		entry.Detail = string(data)
+	}
+	if err := database.DB.Create(&entry).Error; err != nil {
+		fmt.Println("Failed to write audit log", action, err)
+		return err
+	}
+	return nil
+}
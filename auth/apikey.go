@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mcp-server/database"
+	"time"
+)
+
+// API түлхүүрийг JWT-ээс ялгах угтвар
+const APIKeyPrefix = "mcpk_"
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey issues a new key for the user. Only its hash is stored, the
+// returned plaintext key is shown once.
+func CreateAPIKey(user database.User, name string, expiresAt *time.Time) (string, *database.APIKey, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, err
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	record := database.APIKey{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    key[:len(APIKeyPrefix)+6],
+		KeyHash:   hashAPIKey(key),
+		ExpiresAt: expiresAt,
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return "", nil, err
+	}
+	return key, &record, nil
+}
+
+func authenticateAPIKey(key string) (*Identity, error) {
+	var record database.APIKey
+	if err := database.DB.Where("key_hash = ?", hashAPIKey(key)).First(&record).Error; err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	now := time.Now()
+	if record.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: api key %s is revoked", ErrInvalidCredentials, record.Prefix)
+	}
+	if record.ExpiresAt != nil && now.After(*record.ExpiresAt) {
+		return nil, fmt.Errorf("%w: api key %s expired", ErrInvalidCredentials, record.Prefix)
+	}
+
+	user, err := loadUser("id = ?", record.UserID)
+	if err != nil {
+		return nil, err
+	}
+	database.DB.Model(&record).UpdateColumn("last_used_at", now)
+	// API түлхүүр хэрэглэгчийн өөрийн эрхээр бүх хэрэгсэлд хандана
+	return &Identity{User: user, Method: MethodAPIKey, Scopes: AllScopes}, nil
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mcp-server/database"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+const (
+	MethodAPIKey   = "api_key"
+	MethodJWT      = "jwt"
+	MethodOAuth    = "oauth"
+	MethodAction   = "action_link" // Имэйл дэх нэг удаагийн холбоос
+	MethodTelegram = "telegram"    // Telegram товчлуур
+)
+
+// Identity нэвтэрсэн хэрэглэгч, ямар аргаар нэвтэрсэн, зөвшөөрөгдсөн scope
+type Identity struct {
+	User   *database.User
+	Method string
+	Scopes []string
+}
+
+// HasScope reports whether the token granted scope
+func (i *Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the identity stored by Middleware
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
+
+// Middleware rejects requests without a valid API key or bearer JWT and
+// stores the caller's Identity in the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := Authenticate(r)
+		if err != nil {
+			fmt.Println("Authentication failed", r.URL.Path, err)
+			Unauthorized(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+	})
+}
+
+// Authenticate resolves the request credentials: "X-API-Key: <key>" or
+// "Authorization: Bearer <api key | JWT | opaque token>". JWTs are verified
+// locally when keys are configured, other tokens go to the introspection
+// endpoint of the authorization server.
+func Authenticate(r *http.Request) (*Identity, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return authenticateAPIKey(key)
+	}
+
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, ErrMissingCredentials
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrInvalidCredentials
+	}
+	token = strings.TrimSpace(token)
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		return authenticateAPIKey(token)
+	}
+	if jwtEnabled() && strings.Count(token, ".") == 2 {
+		identity, err := authenticateJWT(token)
+		if err == nil || !introspectionEnabled() {
+			return identity, err
+		}
+	}
+	if introspectionEnabled() {
+		return authenticateIntrospection(token)
+	}
+	return nil, fmt.Errorf("%w: bearer tokens are not configured", ErrInvalidCredentials)
+}
+
+// loadUser loads an active user with the relations tools rely on
+func loadUser(query string, args ...interface{}) (*database.User, error) {
+	var user database.User
+	if err := database.DB.Preload("Team").Preload("Role").Where(query, args...).First(&user).Error; err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("%w: user %d is not active", ErrInvalidCredentials, user.ID)
+	}
+	return &user, nil
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"mcp-server/database"
+	"mcp-server/secrets"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Токен шалгахад зөвшөөрөх цагийн зөрүү
+const clockSkew = time.Minute
+
+// JWT шалгах тохиргоо, Configure-оор ачаална
+var verifier struct {
+	hmacSecret []byte
+	rsaKeys    map[string]*rsa.PublicKey // kid -> key
+	issuer     string
+	audience   string
+}
+
+// Claims JWT-ээс ашиглах талбарууд
+type Claims struct {
+	Subject   string          `json:"sub"`
+	Email     string          `json:"email"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt int64           `json:"exp"`
+	NotBefore int64           `json:"nbf"`
+	Scope     *string         `json:"scope"` // nil when the claim is absent
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// Configure loads JWT settings: JWT_HS256_SECRET, JWT_JWKS_FILE (RS256 public
+// keys), JWT_ISSUER and JWT_AUDIENCE, and the OAuth introspection settings.
+// JWT_AUDIENCE defaults to OAUTH_RESOURCE, so like introspected tokens a JWT
+// must name this server in aud. Without any keys only API keys are accepted.
+func Configure() error {
+	configureOAuth()
+
+	verifier.hmacSecret = []byte(secrets.Get("JWT_HS256_SECRET"))
+	verifier.issuer = viper.GetString("JWT_ISSUER")
+	verifier.audience = viper.GetString("JWT_AUDIENCE")
+	if verifier.audience == "" {
+		verifier.audience = oauth.resource
+	}
+	verifier.rsaKeys = map[string]*rsa.PublicKey{}
+
+	path := viper.GetString("JWT_JWKS_FILE")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read JWKS file: %w", err)
+	}
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("parse JWKS file: %w", err)
+	}
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(key.N)
+		e, errE := base64.RawURLEncoding.DecodeString(key.E)
+		if errN != nil || errE != nil {
+			return fmt.Errorf("JWKS key %q has invalid modulus or exponent", key.Kid)
+		}
+		verifier.rsaKeys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(verifier.rsaKeys) == 0 {
+		return fmt.Errorf("JWKS file %s has no RSA signing keys", path)
+	}
+	return nil
+}
+
+// VerifyJWT checks the signature and time/issuer/audience claims of token
+func VerifyJWT(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch header.Alg {
+	case "HS256":
+		if len(verifier.hmacSecret) == 0 {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, verifier.hmacSecret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, errors.New("invalid signature")
+		}
+	case "RS256":
+		key, err := rsaKey(header.Kid)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, errors.New("invalid signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", header.Alg)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return &claims, claims.validate(time.Now())
+}
+
+func jwtEnabled() bool {
+	return len(verifier.hmacSecret) > 0 || len(verifier.rsaKeys) > 0
+}
+
+func rsaKey(kid string) (*rsa.PublicKey, error) {
+	if key, ok := verifier.rsaKeys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(verifier.rsaKeys) == 1 {
+		for _, key := range verifier.rsaKeys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed token segment")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New("malformed token segment")
+	}
+	return nil
+}
+
+func (c *Claims) validate(now time.Time) error {
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("token expired")
+	}
+	if c.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(c.NotBefore, 0)) {
+		return errors.New("token not yet valid")
+	}
+	if verifier.issuer != "" && c.Issuer != verifier.issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	// Ижил issuer өөр сервисэд олгосон токеныг хүлээж авахгүй
+	if !c.HasAudience(verifier.audience) {
+		return fmt.Errorf("token audience is not %s", verifier.audience)
+	}
+	return nil
+}
+
+// HasAudience reports whether aud (string or array) contains audience
+func (c *Claims) HasAudience(audience string) bool {
+	var single string
+	if json.Unmarshal(c.Audience, &single) == nil {
+		return single == audience
+	}
+	var many []string
+	if json.Unmarshal(c.Audience, &many) == nil {
+		for _, aud := range many {
+			if aud == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// userFromClaims maps email, or sub holding an email or user id, to a user
+func userFromClaims(claims *Claims) (*database.User, error) {
+	switch {
+	case claims.Email != "":
+		return loadUser("email = ?", claims.Email)
+	case strings.Contains(claims.Subject, "@"):
+		return loadUser("email = ?", claims.Subject)
+	default:
+		id, err := strconv.ParseUint(claims.Subject, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: subject %q is neither email nor user id", ErrInvalidCredentials, claims.Subject)
+		}
+		return loadUser("id = ?", id)
+	}
+}
+
+func authenticateJWT(token string) (*Identity, error) {
+	claims, err := VerifyJWT(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	user, err := userFromClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{User: user, Method: MethodJWT, Scopes: claims.Scopes()}, nil
+}
+
+// Scopes returns the granted scopes. A token without a scope claim, as issued
+// before scopes existed, gets every scope like an API key and the user's role
+// alone decides which tools it can call; "scope": "" grants nothing.
+func (c *Claims) Scopes() []string {
+	if c.Scope == nil {
+		return AllScopes
+	}
+	return strings.Fields(*c.Scope)
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const testResource = "https://mcp.example.mn"
+
+// configureJWT sets up HS256 verification with OAUTH_RESOURCE as the only
+// audience setting, as a deployment that only configured OAuth would
+func configureJWT(t *testing.T, settings map[string]string) {
+	t.Helper()
+	t.Setenv("JWT_HS256_SECRET", "test-jwt-secret")
+	viper.Set("OAUTH_RESOURCE", testResource)
+	for name, value := range settings {
+		viper.Set(name, value)
+	}
+	t.Cleanup(func() {
+		viper.Set("OAUTH_RESOURCE", "")
+		for name := range settings {
+			viper.Set(name, "")
+		}
+	})
+	if err := Configure(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func signHS256(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte("test-jwt-secret"))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func testClaims(extra map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub": "bat@example.mn",
+		"aud": testResource,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	return claims
+}
+
+func TestJWTScopes(t *testing.T) {
+	configureJWT(t, nil)
+	tests := []struct {
+		name  string
+		scope interface{}
+		want  []string
+	}{
+		// user-033-ийн scope-гүй токенууд эрхийн дагуу бүх хэрэгсэлд хандана
+		{"no scope claim", nil, AllScopes},
+		{"one scope", "absence:read", []string{ScopeAbsenceRead}},
+		{"several scopes", "absence:read  absence:write", []string{ScopeAbsenceRead, ScopeAbsenceWrite}},
+		{"empty scope", "", nil},
+	}
+	for _, tt := range tests {
+		claims, err := VerifyJWT(signHS256(t, testClaims(map[string]interface{}{"scope": tt.scope})))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		identity := &Identity{Method: MethodJWT, Scopes: claims.Scopes()}
+		if !slices.Equal(identity.Scopes, tt.want) {
+			t.Errorf("%s: scopes = %v, want %v", tt.name, identity.Scopes, tt.want)
+		}
+		for _, scope := range AllScopes {
+			if got := identity.HasScope(scope); got != slices.Contains(tt.want, scope) {
+				t.Errorf("%s: HasScope(%s) = %v", tt.name, scope, got)
+			}
+		}
+	}
+}
+
+func TestJWTAudience(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		aud      interface{}
+		ok       bool
+	}{
+		{"resource", nil, testResource, true},
+		{"resource in a list", nil, []string{"https://other.example.mn", testResource}, true},
+		{"no aud", nil, nil, false},
+		{"empty aud", nil, "", false},
+		{"other service", nil, "https://hr.example.mn", false},
+		{"other services", nil, []string{"https://hr.example.mn"}, false},
+		{"JWT_AUDIENCE overrides", map[string]string{"JWT_AUDIENCE": "mcp-server"}, "mcp-server", true},
+		{"JWT_AUDIENCE replaces the resource", map[string]string{"JWT_AUDIENCE": "mcp-server"}, testResource, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configureJWT(t, tt.settings)
+			_, err := VerifyJWT(signHS256(t, testClaims(map[string]interface{}{"aud": tt.aud})))
+			if (err == nil) != tt.ok {
+				t.Errorf("VerifyJWT = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestJWTValidation(t *testing.T) {
+	configureJWT(t, map[string]string{"JWT_ISSUER": "https://auth.example.mn"})
+	issuer := map[string]interface{}{"iss": "https://auth.example.mn"}
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := testClaims(issuer)
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	if _, err := VerifyJWT(signHS256(t, testClaims(issuer))); err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", signHS256(t, with("exp", time.Now().Add(-2*time.Minute).Unix()))},
+		{"no exp", signHS256(t, with("exp", nil))},
+		{"not yet valid", signHS256(t, with("nbf", time.Now().Add(5*time.Minute).Unix()))},
+		{"other issuer", signHS256(t, with("iss", "https://evil.example.mn"))},
+		{"no issuer", signHS256(t, with("iss", nil))},
+		{"tampered payload", tamperPayload(signHS256(t, testClaims(issuer)))},
+		{"alg none", "eyJhbGciOiJub25lIn0." + strings.Split(signHS256(t, testClaims(issuer)), ".")[1] + "."},
+		{"malformed", "a.b"},
+	}
+	for _, tt := range tests {
+		if _, err := VerifyJWT(tt.token); err == nil {
+			t.Errorf("%s: VerifyJWT succeeded", tt.name)
+		}
+	}
+}
+
+func tamperPayload(token string) string {
+	parts := strings.Split(token, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	payload = []byte(strings.Replace(string(payload), "bat@", "bold@", 1))
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mcp-server/secrets"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// MCP хэрэгслүүдийн scope-ууд
+const (
+	ScopeUsersRead      = "users:read"
+	ScopeAbsenceRead    = "absence:read"
+	ScopeAbsenceWrite   = "absence:write"
+	ScopeAbsenceApprove = "absence:approve"
+	ScopeAdmin          = "admin"
+)
+
+var AllScopes = []string{ScopeUsersRead, ScopeAbsenceRead, ScopeAbsenceWrite, ScopeAbsenceApprove, ScopeAdmin}
+
+const defaultIntrospectionCacheTTL = time.Minute
+
+// OAuth 2.1 resource server тохиргоо, Configure-оор ачаална
+var oauth struct {
+	resource         string
+	issuer           string
+	introspectionURL string
+	clientID         string
+	clientSecret     string
+	cacheTTL         time.Duration
+	client           *http.Client
+
+	mu    sync.Mutex
+	cache map[string]introspectionEntry
+}
+
+type introspectionEntry struct {
+	response introspectionResponse
+	expires  time.Time
+}
+
+// RFC 7662 token introspection хариу
+type introspectionResponse struct {
+	Active   bool            `json:"active"`
+	Scope    string          `json:"scope"`
+	Subject  string          `json:"sub"`
+	Username string          `json:"username"`
+	Email    string          `json:"email"`
+	Issuer   string          `json:"iss"`
+	Audience json.RawMessage `json:"aud"`
+	Expires  int64           `json:"exp"`
+}
+
+func configureOAuth() {
+	oauth.resource = viper.GetString("OAUTH_RESOURCE")
+	if oauth.resource == "" {
+		base := viper.GetString("PUBLIC_BASE_URL")
+		if base == "" {
+			base = "http://localhost:8080"
+		}
+		oauth.resource = strings.TrimRight(base, "/")
+	}
+	oauth.issuer = viper.GetString("OAUTH_ISSUER")
+	oauth.introspectionURL = viper.GetString("OAUTH_INTROSPECTION_URL")
+	oauth.clientID = viper.GetString("OAUTH_CLIENT_ID")
+	oauth.clientSecret = secrets.Get("OAUTH_CLIENT_SECRET")
+	oauth.cacheTTL = viper.GetDuration("OAUTH_INTROSPECTION_CACHE_TTL")
+	if oauth.cacheTTL <= 0 {
+		oauth.cacheTTL = defaultIntrospectionCacheTTL
+	}
+	oauth.client = &http.Client{Timeout: 10 * time.Second}
+	oauth.cache = map[string]introspectionEntry{}
+}
+
+// ResourceMetadataURL returns the protected resource metadata document URL
+func ResourceMetadataURL() string {
+	return oauth.resource + "/.well-known/oauth-protected-resource"
+}
+
+// ProtectedResourceHandler serves RFC 9728 metadata so MCP clients can
+// discover the authorization server and the scopes this server accepts.
+func ProtectedResourceHandler(w http.ResponseWriter, r *http.Request) {
+	metadata := map[string]interface{}{
+		"resource":                 oauth.resource,
+		"authorization_servers":    []string{},
+		"scopes_supported":         AllScopes,
+		"bearer_methods_supported": []string{"header"},
+		"resource_name":            "MCP absence server",
+	}
+	if oauth.issuer != "" {
+		metadata["authorization_servers"] = []string{oauth.issuer}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(metadata)
+}
+
+// Unauthorized writes a 401 with a Bearer challenge pointing at the resource metadata
+func Unauthorized(w http.ResponseWriter, err error) {
+	challenge := fmt.Sprintf(`Bearer resource_metadata=%q`, ResourceMetadataURL())
+	if err != nil && !errors.Is(err, ErrMissingCredentials) {
+		challenge += `, error="invalid_token", error_description="the access token is invalid or expired"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// InsufficientScope writes a 403 asking the client to obtain scope
+func InsufficientScope(w http.ResponseWriter, scope string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q, resource_metadata=%q`, scope, ResourceMetadataURL()))
+	http.Error(w, "Insufficient scope: "+scope+" is required", http.StatusForbidden)
+}
+
+// RequireScope reports whether the request identity has scope, writing the
+// 403 challenge when it does not.
+func RequireScope(w http.ResponseWriter, r *http.Request, scope string) bool {
+	identity, ok := FromContext(r.Context())
+	if !ok {
+		Unauthorized(w, nil)
+		return false
+	}
+	if !identity.HasScope(scope) {
+		InsufficientScope(w, scope)
+		return false
+	}
+	return true
+}
+
+func introspectionEnabled() bool {
+	return oauth.introspectionURL != ""
+}
+
+// introspect asks the authorization server about token, caching the answer
+// for a short time so every tool call does not cost a round trip.
+func introspect(token string) (*introspectionResponse, error) {
+	sum := sha256.Sum256([]byte(token))
+	cacheKey := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	oauth.mu.Lock()
+	entry, ok := oauth.cache[cacheKey]
+	oauth.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return &entry.response, nil
+	}
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequest(http.MethodPost, oauth.introspectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if oauth.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(oauth.clientID), url.QueryEscape(oauth.clientSecret))
+	}
+	resp, err := oauth.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection returned %s", resp.Status)
+	}
+	var result introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("introspection response: %w", err)
+	}
+
+	expires := now.Add(oauth.cacheTTL)
+	if result.Expires != 0 && time.Unix(result.Expires, 0).Before(expires) {
+		expires = time.Unix(result.Expires, 0)
+	}
+	oauth.mu.Lock()
+	for key, e := range oauth.cache {
+		if now.After(e.expires) {
+			delete(oauth.cache, key)
+		}
+	}
+	oauth.cache[cacheKey] = introspectionEntry{response: result, expires: expires}
+	oauth.mu.Unlock()
+	return &result, nil
+}
+
+// authenticateIntrospection accepts an active token whose aud contains
+// OAUTH_RESOURCE and, when OAUTH_ISSUER is set, whose iss matches it
+func authenticateIntrospection(token string) (*Identity, error) {
+	result, err := introspect(token)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Active {
+		return nil, fmt.Errorf("%w: token is not active", ErrInvalidCredentials)
+	}
+	if result.Expires != 0 && time.Now().After(time.Unix(result.Expires, 0)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+	// iss болон aud байхгүй бол өөр сервисэд олгосон токен байж болох тул татгалзана
+	if oauth.issuer != "" && result.Issuer != oauth.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidCredentials, result.Issuer)
+	}
+	claims := Claims{Subject: result.Subject, Email: result.Email, Audience: result.Audience}
+	if !claims.HasAudience(oauth.resource) {
+		return nil, fmt.Errorf("%w: token was not issued for %s", ErrInvalidCredentials, oauth.resource)
+	}
+	if claims.Email == "" && strings.Contains(result.Username, "@") {
+		claims.Email = result.Username
+	}
+
+	user, err := userFromClaims(&claims)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{User: user, Method: MethodOAuth, Scopes: strings.Fields(result.Scope)}, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mcp-server/auth"
+	"mcp-server/database"
+	"mcp-server/secrets"
+	"mcp-server/telegram"
+	"os"
+	"time"
+)
+
+// RunCommand executes an administrative command given on the command line
+func RunCommand(args []string) error {
+	switch args[0] {
+	case "create-api-key":
+		// create-api-key <email> [name] [expires YYYY-MM-DD]
+		if len(args) < 2 {
+			return errors.New("usage: create-api-key <email> [name] [expires YYYY-MM-DD]")
+		}
+		var user database.User
+		if err := database.DB.Where("email = ?", args[1]).First(&user).Error; err != nil {
+			return fmt.Errorf("user %s not found", args[1])
+		}
+		name := ""
+		if len(args) > 2 {
+			name = args[2]
+		}
+		var expiresAt *time.Time
+		if len(args) > 3 {
+			t, err := time.ParseInLocation("2006-01-02", args[3], time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid expiry date %q", args[3])
+			}
+			expiresAt = &t
+		}
+		key, record, err := auth.CreateAPIKey(user, name, expiresAt)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("API key %d (%s) for %s:\n%s\n", record.ID, record.Prefix, user.Email, key)
+		return nil
+
+	case "revoke-api-key":
+		// revoke-api-key <prefix>
+		if len(args) < 2 {
+			return errors.New("usage: revoke-api-key <prefix>")
+		}
+		res := database.DB.Model(&database.APIKey{}).Where("prefix = ? AND revoked_at IS NULL", args[1]).Update("revoked_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		fmt.Printf("Revoked %d key(s)\n", res.RowsAffected)
+		return nil
+
+	case "backfill-rd":
+		// backfill-rd: registration_number -> encrypted_rd, plaintext хоослоно
+		return BackfillRegistrationNumbers()
+
+	case "rotate-rd":
+		// rotate-rd: хуучин түлхүүрээр шифрлэсэн утгыг CIPHER_KEY_VERSION руу
+		return RotateRegistrationNumbers()
+
+	case "seal-secrets":
+		// seal-secrets <secrets.json> <output>: SECRETS_FILE-д зориулж SECRETS_KEY-ээр шифрлэнэ
+		if len(args) < 3 {
+			return errors.New("usage: seal-secrets <secrets.json> <output>")
+		}
+		key, _, err := secrets.Lookup("SECRETS_KEY")
+		if err != nil {
+			return fmt.Errorf("SECRETS_KEY: %w", err)
+		}
+		plain, err := os.ReadFile(args[1])
+		if err != nil {
+			return err
+		}
+		var values map[string]string
+		if err := json.Unmarshal(plain, &values); err != nil {
+			return fmt.Errorf("%s must be a JSON object of strings: %w", args[1], err)
+		}
+		sealed, err := secrets.Seal(key, plain)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(args[2], []byte(sealed+"\n"), 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Sealed %d secret(s) into %s\n", len(values), args[2])
+		return nil
+
+	case "set-telegram-webhook":
+		// set-telegram-webhook, PUBLIC_BASE_URL/telegram/webhook-ийг бүртгэнэ
+		if telegram.Default == nil {
+			return errors.New("TELEGRAM_BOT_TOKEN is not set")
+		}
+		secret := secrets.Get("TELEGRAM_WEBHOOK_SECRET")
+		if secret == "" {
+			return errors.New("TELEGRAM_WEBHOOK_SECRET is not set")
+		}
+		url := PublicURL("/telegram/webhook")
+		if err := telegram.Default.SetWebhook(context.Background(), url, secret); err != nil {
+			return err
+		}
+		fmt.Println("Telegram webhook set to", url)
+		return nil
+
+	default:
+		return errors.New("unknown command, available: create-api-key, revoke-api-key, backfill-rd, rotate-rd, seal-secrets, set-telegram-webhook")
+	}
+}
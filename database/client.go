@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"mcp-server/secrets"
 
 	"github.com/spf13/viper"
 	"gorm.io/driver/postgres"
@@ -38,7 +39,7 @@ func CreateClient() *gorm.DB {
 		dbPort,
 		dbUser,
 		dbName,
-		viper.GetString("DB_PASSWORD"),
+		secrets.Get("DB_PASSWORD"),
 		dbTimezone,
 	)
 	
@@ -62,3 +63,31 @@ func CreateClient() *gorm.DB {
 	DB = db
 	return db
 }
+
+// AutoMigrate creates the tables and columns owned by this server
+func AutoMigrate() {
+	if err := DB.AutoMigrate(&Absence{}, &AbsenceReason{}, &APIKey{}, &AuditLog{}, &ActionToken{}, &OutboxMessage{}, &UserPreference{}); err != nil {
+		panic(err.Error())
+	}
+	seedAbsenceReasons()
+}
+
+// Анхны шалтгааны каталог, хүснэгт хоосон үед л нэмнэ
+var defaultAbsenceReasons = []AbsenceReason{
+	{Code: "vacation", NameEN: "Annual leave", NameMN: "Ээлжийн амралт", IsPaid: true, CountsAgainstBalance: true, NoticeDays: 7},
+	{Code: "sick", NameEN: "Sick leave", NameMN: "Өвчтэй", IsPaid: true, RequiresAttachment: true, MaxDays: 10},
+	{Code: "personal", NameEN: "Personal", NameMN: "Хувийн шалтгаан", MaxDays: 3},
+	{Code: "medical_appointment", NameEN: "Medical appointment", NameMN: "Эмчийн үзлэг", IsPaid: true, MaxDays: 1},
+	{Code: "family", NameEN: "Family event", NameMN: "Гэр бүлийн шалтгаан", IsPaid: true, MaxDays: 3},
+	{Code: "unpaid", NameEN: "Unpaid leave", NameMN: "Цалингүй чөлөө", NoticeDays: 3},
+}
+
+func seedAbsenceReasons() {
+	var count int64
+	if err := DB.Model(&AbsenceReason{}).Count(&count).Error; err != nil || count > 0 {
+		return
+	}
+	if err := DB.Create(&defaultAbsenceReasons).Error; err != nil {
+		fmt.Println("Failed to seed absence reasons", err)
+	}
+}
@@ -101,9 +101,86 @@ type (
 		CreatedUser   *User     `gorm:"foreignKey:CreatedUserID" json:"created_user"`  //  Created User
 		RemainHours   float64   `gorm:"column:remain_hours" json:"remain_hours"`       // Remain Hours
 		StartDate     time.Time `gorm:"column:start_date" json:"start_date"`           // Start Date
+		EndDate       time.Time `gorm:"column:end_date" json:"end_date"`               // End Date
+		Kind          string    `gorm:"column:kind;default:full_day" json:"kind"`      // full_day, half_day_am, half_day_pm, hourly
 		Status        string    `gorm:"column:status" json:"status"`
 		LeaderID      uint      `gorm:"column:leader_id" json:"leader_id"`
 		Leader        *User     `gorm:"foreignKey:LeaderID" json:"leader"`
+		Attachments   []*File   `gorm:"many2many:absence_attachments" json:"attachments,omitempty"` // Хавсралт файлууд
+
+		// Удирдагчийн шийдвэр
+		DecidedByID *uint      `gorm:"column:decided_by_id" json:"decided_by_id"`       // Шийдвэрлэсэн хэрэглэгч
+		DecidedAt   *time.Time `gorm:"column:decided_at" json:"decided_at"`             // Шийдвэрлэсэн огноо
+		Comment     string     `gorm:"column:decision_comment" json:"decision_comment"` // Шийдвэрийн тайлбар
+
+		// Цуцлалт, шийдвэрийн тайлбарыг дарж бичихгүй
+		CancelComment string `gorm:"column:cancel_comment" json:"cancel_comment"` // Цуцалсан шалтгаан
+	}
+
+	AbsenceReason struct {
+		Base
+		Code                 string  `gorm:"column:code;unique;not null" json:"code"`                                   // Шалтгааны код
+		NameEN               string  `gorm:"column:name_en;not null" json:"name_en"`                                    // Англи нэр
+		NameMN               string  `gorm:"column:name_mn;not null" json:"name_mn"`                                    // Монгол нэр
+		IsPaid               bool    `gorm:"column:is_paid;default:false" json:"is_paid"`                               // Цалинтай эсэх
+		CountsAgainstBalance bool    `gorm:"column:counts_against_balance;default:false" json:"counts_against_balance"` // Ээлжийн амралтаас хасагдах эсэх
+		RequiresAttachment   bool    `gorm:"column:requires_attachment;default:false" json:"requires_attachment"`       // Хавсралт шаардах эсэх
+		MaxDays              float64 `gorm:"column:max_days;default:0" json:"max_days"`                                 // Нэг удаад авах дээд хоног, 0 бол хязгааргүй
+		NoticeDays           int     `gorm:"column:notice_days;default:0" json:"notice_days"`                           // Хэдэн хоногийн өмнө мэдэгдэх
+		IsActive             bool    `gorm:"column:is_active;default:true" json:"is_active"`                            // Идэвхтэй эсэх
+	}
+
+	APIKey struct {
+		Base
+		UserID     uint       `gorm:"column:user_id;not null;index" json:"user_id"` // Эзэмшигч
+		User       *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`      //
+		Name       string     `gorm:"column:name" json:"name"`                      // Хаана ашиглах тайлбар
+		Prefix     string     `gorm:"column:prefix" json:"prefix"`                  // Танихад зориулсан эхний тэмдэгтүүд
+		KeyHash    string     `gorm:"column:key_hash;unique;not null" json:"-"`     // SHA-256
+		LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`      //
+		ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expires_at"`          //
+		RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at"`          //
+	}
+
+	AuditLog struct {
+		Base
+		ActorID     uint   `gorm:"column:actor_id;not null;index" json:"actor_id"`   // Үйлдэл хийсэн хэрэглэгч
+		Action      string `gorm:"column:action;not null;index" json:"action"`       // view_sensitive гэх мэт
+		SubjectType string `gorm:"column:subject_type;not null" json:"subject_type"` // user, absence
+		SubjectIDs  string `gorm:"column:subject_ids" json:"subject_ids"`            // JSON массив
+		Detail      string `gorm:"column:detail" json:"detail"`                      // Нэмэлт мэдээлэл, JSON
+	}
+
+	UserPreference struct {
+		Base
+		UserID   uint   `gorm:"column:user_id;not null;uniqueIndex" json:"user_id"` // Хэрэглэгч
+		Language string `gorm:"column:language" json:"language"`                    // Мэдэгдлийн хэл: mn, en
+	}
+
+	ActionToken struct {
+		Base
+		Action    string     `gorm:"column:action;not null" json:"action"`               // approved, rejected
+		AbsenceID uint       `gorm:"column:absence_id;not null;index" json:"absence_id"` // Шийдэх хүсэлт
+		UserID    uint       `gorm:"column:user_id;not null" json:"user_id"`             // Холбоос илгээсэн удирдагч
+		TokenHash string     `gorm:"column:token_hash;unique;not null" json:"-"`         // SHA-256
+		ExpiresAt time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`       //
+		UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`                      // Ашигласан эсвэл хүчингүй болсон
+	}
+
+	OutboxMessage struct {
+		Base
+		Channel       string     `gorm:"column:channel;not null;default:email" json:"channel"`       // email, telegram
+		Template      string     `gorm:"column:template;index" json:"template"`                      // Имэйлийн загвар
+		Recipient     string     `gorm:"column:recipient" json:"recipient"`                          // Хүлээн авагчид, таслалаар
+		Subject       string     `gorm:"column:subject" json:"subject"`                              //
+		Payload       string     `gorm:"column:payload;type:text;not null" json:"-"`                 // smtp.Message эсвэл telegram.Message, JSON. Илгээсний дараа хоосолно
+		Status        string     `gorm:"column:status;not null;default:pending;index" json:"status"` // pending, sending, sent, dead
+		Attempts      int        `gorm:"column:attempts;not null;default:0" json:"attempts"`         // Оролдлогын тоо
+		NextAttemptAt time.Time  `gorm:"column:next_attempt_at;index" json:"next_attempt_at"`        // Дараагийн оролдлого
+		LastError     string     `gorm:"column:last_error" json:"last_error"`                        // Сүүлийн алдаа
+		SentAt        *time.Time `gorm:"column:sent_at" json:"sent_at"`                              // Илгээсэн огноо
+		LeaseUntil    *time.Time `gorm:"column:lease_until" json:"lease_until"`                      // sending төлөвт worker-ийн эзэмших хугацаа
+		AbsenceID     *uint      `gorm:"column:absence_id;index" json:"absence_id"`                  // Холбогдох хүсэлт
 	}
 
 	TimeInterval struct {
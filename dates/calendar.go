@@ -0,0 +1,140 @@
+package dates
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Calendar describes the working day used to validate and measure absences
+type Calendar struct {
+	WorkStart  time.Duration         // Ажил эхлэх цаг (шөнө дундаас хойш)
+	LunchStart time.Duration         // Цайны цаг эхлэх
+	LunchEnd   time.Duration         // Цайны цаг дуусах
+	WorkEnd    time.Duration         // Ажил тарах цаг
+	Weekend    map[time.Weekday]bool // Амралтын өдрүүд
+	Holidays   map[string]bool       // Нийтээр амрах өдөр: "2006-01-02" эсвэл жил бүр давтагдах "01-02"
+}
+
+var calendar = &Calendar{
+	WorkStart:  9 * time.Hour,
+	LunchStart: 13 * time.Hour,
+	LunchEnd:   14 * time.Hour,
+	WorkEnd:    18 * time.Hour,
+	Weekend:    map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+	Holidays:   map[string]bool{},
+}
+
+// WorkingCalendar returns the configured working calendar
+func WorkingCalendar() *Calendar {
+	return calendar
+}
+
+// ConfigureCalendar builds the working calendar from config values. Empty
+// values keep the defaults (09:00-18:00, lunch 13:00-14:00, Sat/Sun off).
+func ConfigureCalendar(workStart, lunchStart, lunchEnd, workEnd, weekend, holidays string) error {
+	c := *calendar
+	for _, f := range []struct {
+		value  string
+		target *time.Duration
+	}{
+		{workStart, &c.WorkStart},
+		{lunchStart, &c.LunchStart},
+		{lunchEnd, &c.LunchEnd},
+		{workEnd, &c.WorkEnd},
+	} {
+		if f.value == "" {
+			continue
+		}
+		d, err := parseClock(f.value)
+		if err != nil {
+			return err
+		}
+		*f.target = d
+	}
+	if !(c.WorkStart <= c.LunchStart && c.LunchStart <= c.LunchEnd && c.LunchEnd <= c.WorkEnd) {
+		return fmt.Errorf("working hours must be ordered start <= lunch start <= lunch end <= end")
+	}
+
+	if weekend != "" {
+		c.Weekend = map[time.Weekday]bool{}
+		for _, name := range strings.Split(weekend, ",") {
+			wd, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return fmt.Errorf("unknown weekday %q", name)
+			}
+			c.Weekend[wd] = true
+		}
+	}
+
+	c.Holidays = map[string]bool{}
+	for _, day := range strings.Split(holidays, ",") {
+		if day = strings.TrimSpace(day); day != "" {
+			c.Holidays[day] = true
+		}
+	}
+
+	calendar = &c
+	return nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock time %q, expected HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsWorkingDay reports whether t's business day is neither weekend nor holiday
+func (c *Calendar) IsWorkingDay(t time.Time) bool {
+	t = t.In(location)
+	if c.Weekend[t.Weekday()] {
+		return false
+	}
+	return !c.Holidays[t.Format(DateLayout)] && !c.Holidays[t.Format("01-02")]
+}
+
+// At returns the time offset from midnight on t's business day
+func (c *Calendar) At(t time.Time, offset time.Duration) time.Time {
+	day := StartOfDay(t)
+	return time.Date(day.Year(), day.Month(), day.Day(), int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, location)
+}
+
+// DayHours returns the number of working hours in a full working day
+func (c *Calendar) DayHours() float64 {
+	return (c.LunchStart - c.WorkStart + c.WorkEnd - c.LunchEnd).Hours()
+}
+
+// WorkingHours returns the working time between from and to, skipping
+// nights, lunch breaks, weekends and holidays.
+func (c *Calendar) WorkingHours(from, to time.Time) float64 {
+	var total time.Duration
+	for day := StartOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !c.IsWorkingDay(day) {
+			continue
+		}
+		total += overlap(from, to, c.At(day, c.WorkStart), c.At(day, c.LunchStart))
+		total += overlap(from, to, c.At(day, c.LunchEnd), c.At(day, c.WorkEnd))
+	}
+	return total.Hours()
+}
+
+// WithinWorkingDay reports whether [from, to] lies inside from's working hours
+func (c *Calendar) WithinWorkingDay(from, to time.Time) bool {
+	return !from.Before(c.At(from, c.WorkStart)) && !to.After(c.At(from, c.WorkEnd))
+}
+
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	start, end := aStart, aEnd
+	if bStart.After(start) {
+		start = bStart
+	}
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if end.After(start) {
+		return end.Sub(start)
+	}
+	return 0
+}
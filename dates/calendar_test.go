@@ -0,0 +1,82 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkingHours(t *testing.T) {
+	ulaanbaatar(t)
+	cal := &Calendar{
+		WorkStart:  9 * time.Hour,
+		LunchStart: 13 * time.Hour,
+		LunchEnd:   14 * time.Hour,
+		WorkEnd:    18 * time.Hour,
+		Weekend:    map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		Holidays:   map[string]bool{"03-08": true, "2024-03-12": true},
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, Location())
+	}
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     float64
+	}{
+		{"full Monday", at(4, 9, 0), at(4, 18, 0), 8},
+		{"Monday to Tuesday", at(4, 9, 0), at(5, 18, 0), 16},
+		{"morning", at(4, 9, 0), at(4, 13, 0), 4},
+		{"afternoon", at(4, 14, 0), at(4, 18, 0), 4},
+		{"two hours", at(4, 10, 0), at(4, 12, 0), 2},
+		{"across lunch", at(4, 12, 0), at(4, 15, 0), 2},
+		{"inside lunch", at(4, 13, 0), at(4, 14, 0), 0},
+		{"before and after work", at(4, 7, 0), at(4, 20, 0), 8},
+		{"overnight", at(4, 17, 0), at(5, 10, 0), 2},
+		{"Friday to Monday skips the weekend", at(1, 9, 0), at(4, 18, 0), 16},
+		{"weekend only", at(9, 9, 0), at(10, 18, 0), 0},
+		{"yearly holiday 03-08", at(7, 9, 0), at(11, 18, 0), 16},
+		{"dated holiday 2024-03-12", at(11, 9, 0), at(13, 18, 0), 16},
+		{"two weeks", at(4, 9, 0), at(15, 18, 0), 8 * 8},
+		{"empty", at(4, 10, 0), at(4, 10, 0), 0},
+	}
+	for _, tt := range tests {
+		if got := cal.WorkingHours(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: WorkingHours = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := cal.DayHours(); got != 8 {
+		t.Errorf("DayHours = %v, want 8", got)
+	}
+}
+
+func TestConfigureCalendar(t *testing.T) {
+	prev := calendar
+	t.Cleanup(func() { calendar = prev })
+
+	if err := ConfigureCalendar("08:30", "12:30", "13:30", "17:30", "Friday, saturday", "01-01, 2024-03-12"); err != nil {
+		t.Fatal(err)
+	}
+	cal := WorkingCalendar()
+	if cal.WorkStart != 8*time.Hour+30*time.Minute || cal.WorkEnd != 17*time.Hour+30*time.Minute || cal.DayHours() != 8 {
+		t.Errorf("hours = %s-%s (%v)", cal.WorkStart, cal.WorkEnd, cal.DayHours())
+	}
+	if !cal.Weekend[time.Friday] || !cal.Weekend[time.Saturday] || cal.Weekend[time.Sunday] {
+		t.Errorf("weekend = %v", cal.Weekend)
+	}
+	if !cal.Holidays["01-01"] || !cal.Holidays["2024-03-12"] {
+		t.Errorf("holidays = %v", cal.Holidays)
+	}
+
+	for _, bad := range [][6]string{
+		{"9am", "", "", "", "", ""},
+		{"", "", "", "", "someday", ""},
+		{"14:00", "13:00", "", "", "", ""},
+	} {
+		if err := ConfigureCalendar(bad[0], bad[1], bad[2], bad[3], bad[4], bad[5]); err == nil {
+			t.Errorf("ConfigureCalendar(%q) succeeded", bad)
+		}
+	}
+	if WorkingCalendar() != cal {
+		t.Errorf("a failed ConfigureCalendar replaced the calendar")
+	}
+}
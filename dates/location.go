@@ -0,0 +1,75 @@
+package dates
+
+import (
+	"fmt"
+	"time"
+
+	// Alpine image-д zoneinfo байхгүй тул Go-той хамт суулгана
+	_ "time/tzdata"
+)
+
+const (
+	DateLayout     = "2006-01-02"
+	DateTimeLayout = "2006-01-02 15:04"
+)
+
+// Business location, every day boundary is computed in it
+var location = time.Local
+
+// Configure sets the business location (DB_TIMEZONE). It also replaces
+// time.Local because the postgres driver decodes timestamptz values into
+// time.Local and encoding/json writes them with that offset; keeping a single
+// zone makes parsed input, stored rows and API output agree.
+func Configure(name string) error {
+	if name == "" {
+		return fmt.Errorf("timezone is not set")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("load timezone %q: %w", name, err)
+	}
+	location = loc
+	time.Local = loc
+	return nil
+}
+
+// Location returns the configured business location
+func Location() *time.Location {
+	return location
+}
+
+// Now returns the current time in the business location
+func Now() time.Time {
+	return time.Now().In(location)
+}
+
+// Parse resolves user input in the business location relative to now
+func Parse(input string) (Resolved, error) {
+	return Resolve(input, Now(), location)
+}
+
+// StartOfDay returns midnight of t's calendar day in the business location
+func StartOfDay(t time.Time) time.Time {
+	t = t.In(location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+}
+
+// EndOfDay returns midnight of the following day, exclusive upper bound of t's day
+func EndOfDay(t time.Time) time.Time {
+	return StartOfDay(t).AddDate(0, 0, 1)
+}
+
+// SameDay reports whether a and b fall on the same business day
+func SameDay(a, b time.Time) bool {
+	return StartOfDay(a).Equal(StartOfDay(b))
+}
+
+// FormatDate formats t as a business-local date
+func FormatDate(t time.Time) string {
+	return t.In(location).Format(DateLayout)
+}
+
+// FormatDateTime formats t as a business-local date and time
+func FormatDateTime(t time.Time) string {
+	return t.In(location).Format(DateTimeLayout)
+}
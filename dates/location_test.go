@@ -0,0 +1,136 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+// ulaanbaatar runs the test on a UTC host with Asia/Ulaanbaatar (UTC+8) as
+// the business zone and restores both afterwards
+func ulaanbaatar(t *testing.T) {
+	t.Helper()
+	prevLocal, prevLocation := time.Local, location
+	t.Cleanup(func() {
+		time.Local, location = prevLocal, prevLocation
+	})
+	time.Local = time.UTC
+	location = time.UTC
+	if err := Configure("Asia/Ulaanbaatar"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func utc(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseBusinessZone(t *testing.T) {
+	ulaanbaatar(t)
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"2024-03-05 00:00", utc("2024-03-04T16:00:00Z")},
+		{"2024-03-05 23:59", utc("2024-03-05T15:59:00Z")},
+		{"2024-03-05T00:00", utc("2024-03-04T16:00:00Z")},
+		{"2024-03-05", utc("2024-03-04T16:00:00Z")},
+		{"2024-03-05T23:59:00Z", utc("2024-03-05T23:59:00Z")},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if !got.Time.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, got.Time.UTC(), tt.want)
+		}
+	}
+}
+
+func TestDayBoundsBusinessZone(t *testing.T) {
+	ulaanbaatar(t)
+	tests := []struct {
+		name       string
+		in         time.Time
+		start, end time.Time
+	}{
+		{"local midnight", utc("2024-03-04T16:00:00Z"), utc("2024-03-04T16:00:00Z"), utc("2024-03-05T16:00:00Z")},
+		{"local 23:59", utc("2024-03-05T15:59:00Z"), utc("2024-03-04T16:00:00Z"), utc("2024-03-05T16:00:00Z")},
+		// UTC-д өмнөх өдөр боловч Улаанбаатарт 03-05
+		{"local 00:30 in UTC", utc("2024-03-04T16:30:00Z"), utc("2024-03-04T16:00:00Z"), utc("2024-03-05T16:00:00Z")},
+		// UTC-д 03-05 боловч Улаанбаатарт 03-06
+		{"UTC 16:00", utc("2024-03-05T16:00:00Z"), utc("2024-03-05T16:00:00Z"), utc("2024-03-06T16:00:00Z")},
+	}
+	for _, tt := range tests {
+		if got := StartOfDay(tt.in); !got.Equal(tt.start) {
+			t.Errorf("%s: StartOfDay = %s, want %s", tt.name, got.UTC(), tt.start)
+		}
+		if got := EndOfDay(tt.in); !got.Equal(tt.end) {
+			t.Errorf("%s: EndOfDay = %s, want %s", tt.name, got.UTC(), tt.end)
+		}
+		if got := StartOfDay(tt.in).Location(); got.String() != "Asia/Ulaanbaatar" {
+			t.Errorf("%s: StartOfDay location = %s", tt.name, got)
+		}
+	}
+}
+
+// get_time_intervals and list_absences bind `end_date >= StartOfDay(start)`.
+// The bound must be local midnight of the parsed day whatever the input's
+// form or offset, so an absence ending 00:30 local, still the previous UTC
+// day, is selected and one ending 23:59 the day before is not.
+func TestIntervalQueryBound(t *testing.T) {
+	ulaanbaatar(t)
+	tests := []struct {
+		input string
+		bound time.Time
+	}{
+		{"2024-03-05", utc("2024-03-04T16:00:00Z")},
+		{"2024-03-05 00:00", utc("2024-03-04T16:00:00Z")},
+		{"2024-03-05 23:59", utc("2024-03-04T16:00:00Z")},
+		// 16:30 UTC 03-04 нь Улаанбаатарт 03-05 00:30
+		{"2024-03-04T16:30:00Z", utc("2024-03-04T16:00:00Z")},
+		// 15:59 UTC 03-04 нь Улаанбаатарт 03-04 23:59
+		{"2024-03-04T15:59:00Z", utc("2024-03-03T16:00:00Z")},
+	}
+	for _, tt := range tests {
+		start, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.input, err)
+		}
+		bound := StartOfDay(start.Time)
+		if !bound.Equal(tt.bound) {
+			t.Errorf("StartOfDay(%q) = %s, want %s", tt.input, bound.UTC(), tt.bound)
+		}
+		if end := EndOfDay(start.Time); !end.Equal(tt.bound.Add(24 * time.Hour)) {
+			t.Errorf("EndOfDay(%q) = %s, want %s", tt.input, end.UTC(), tt.bound.Add(24*time.Hour))
+		}
+	}
+
+	day := utc("2024-03-04T16:00:00Z")
+	ends := []struct {
+		end     time.Time
+		sameDay bool
+		text    string
+	}{
+		{utc("2024-03-04T16:30:00Z"), true, "2024-03-05 00:30"},
+		{utc("2024-03-04T16:00:00Z"), true, "2024-03-05 00:00"},
+		{utc("2024-03-04T15:59:00Z"), false, "2024-03-04 23:59"},
+		{utc("2024-03-05T15:59:00Z"), true, "2024-03-05 23:59"},
+	}
+	for _, e := range ends {
+		if got := SameDay(e.end, day); got != e.sameDay {
+			t.Errorf("SameDay(%s, 2024-03-05) = %v, want %v", e.end, got, e.sameDay)
+		}
+		if got := StartOfDay(e.end).Equal(day); got != e.sameDay {
+			t.Errorf("StartOfDay(%s) = %s", e.end, StartOfDay(e.end).UTC())
+		}
+		if got := FormatDateTime(e.end); got != e.text {
+			t.Errorf("FormatDateTime(%s) = %s, want %s", e.end, got, e.text)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	PartMorning   = "morning"   // Өглөө
+	PartAfternoon = "afternoon" // Үдээс хойш
+)
+
+// Resolved is an absolute date produced from user input
+type Resolved struct {
+	Time    time.Time // Resolved time in the requested location
+	HasTime bool      // Input carried a time of day
+	Part    string    // Day part (morning/afternoon) when it was given by name
+}
+
+// Layouts carrying their own zone offset
+var zonedLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04Z07:00",
+}
+
+// Date-time layouts without zone, interpreted in the given location
+var localLayouts = []string{
+	"2006-01-02T15:04:05.000",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04",
+}
+
+// Date-only layouts
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"2006.01.02",
+}
+
+var relativeDays = map[string]int{
+	"today":              0,
+	"tomorrow":           1,
+	"day after tomorrow": 2,
+	"yesterday":          -1,
+	"өнөөдөр":            0,
+	"өнөө":               0, // "өнөө орой"
+	"маргааш":            1,
+	"нөгөөдөр":           2,
+	"өчигдөр":            -1,
+}
+
+var weekdays = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday, "даваа": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "мягмар": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "лхагва": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "пүрэв": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday, "баасан": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday, "бямба": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday, "ням": time.Sunday,
+}
+
+// Day part name -> hour, minute, part
+var dayParts = []struct {
+	Word   string
+	Hour   int
+	Minute int
+	Part   string
+}{
+	{"tonight", 18, 0, ""},
+	{"үдээс хойш", 14, 0, PartAfternoon},
+	{"afternoon", 14, 0, PartAfternoon},
+	{"morning", 9, 0, PartMorning},
+	{"өглөө", 9, 0, PartMorning},
+	{"noon", 12, 0, ""},
+	{"evening", 18, 0, ""},
+	{"орой", 18, 0, ""},
+}
+
+var (
+	clockRe     = regexp.MustCompile(`(\d{1,2}):(\d{2})\s*(am|pm)?`)
+	meridiemRe  = regexp.MustCompile(`(\d{1,2})\s*(am|pm)`)
+	mnHourRe    = regexp.MustCompile(`(\d{1,2})\s*цагт`)
+	inDaysRe    = regexp.MustCompile(`^(?:in|after) (\d+) (days?|weeks?)$`)
+	daysLaterRe = regexp.MustCompile(`^(\d+) (days?|weeks?) (?:later|from now)$`)
+	mnDaysRe    = regexp.MustCompile(`^(\d+) (өдөр|өдрийн|хоног|хоногийн|долоо хоног|долоо хоногийн) дараа$`)
+)
+
+// Resolve converts an ISO 8601 date, a date-time without zone or a relative
+// English/Mongolian expression ("next monday", "маргааш өглөө") into an
+// absolute time. Inputs without an explicit offset are interpreted in loc,
+// relative expressions are counted from now.
+func Resolve(input string, now time.Time, loc *time.Location) (Resolved, error) {
+	s := strings.TrimSpace(input)
+	if s == "" {
+		return Resolved{}, fmt.Errorf("empty date")
+	}
+
+	for _, layout := range zonedLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return Resolved{Time: t.In(loc), HasTime: true}, nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return Resolved{Time: t, HasTime: true}, nil
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return Resolved{Time: t}, nil
+		}
+	}
+
+	resolved, ok := resolveRelative(strings.ToLower(s), now.In(loc), loc)
+	if !ok {
+		return Resolved{}, fmt.Errorf("unrecognised date %q: use YYYY-MM-DD, YYYY-MM-DD HH:MM, ISO 8601 or an expression like \"tomorrow\", \"next monday\"", input)
+	}
+	return resolved, nil
+}
+
+func resolveRelative(s string, now time.Time, loc *time.Location) (Resolved, bool) {
+	s = strings.Join(strings.Fields(strings.ReplaceAll(s, ",", " ")), " ")
+	if s == "now" || s == "одоо" {
+		return Resolved{Time: now.Truncate(time.Minute), HasTime: true}, true
+	}
+
+	hour, minute, hasTime, part, s, ok := extractTimeOfDay(s)
+	if !ok {
+		return Resolved{}, false
+	}
+
+	day, ok := resolveDay(s, now)
+	if !ok {
+		return Resolved{}, false
+	}
+
+	result := Resolved{Time: time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc), Part: part}
+	if hasTime {
+		result.Time = time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+		result.HasTime = true
+	}
+	return result, true
+}
+
+// extractTimeOfDay removes a clock time or day part from s and returns it
+func extractTimeOfDay(s string) (hour, minute int, hasTime bool, part, rest string, ok bool) {
+	rest = s
+	if m := clockRe.FindStringSubmatch(rest); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		minute, _ = strconv.Atoi(m[2])
+		if hour, ok = applyMeridiem(hour, m[3]); !ok || minute > 59 {
+			return 0, 0, false, "", "", false
+		}
+		rest = strings.Replace(rest, m[0], "", 1)
+		hasTime = true
+	} else if m := meridiemRe.FindStringSubmatch(rest); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		if hour, ok = applyMeridiem(hour, m[2]); !ok {
+			return 0, 0, false, "", "", false
+		}
+		rest = strings.Replace(rest, m[0], "", 1)
+		hasTime = true
+	} else if m := mnHourRe.FindStringSubmatch(rest); m != nil {
+		hour, _ = strconv.Atoi(m[1])
+		if hour > 23 {
+			return 0, 0, false, "", "", false
+		}
+		rest = strings.Replace(rest, m[0], "", 1)
+		hasTime = true
+	}
+
+	for _, dp := range dayParts {
+		if !strings.Contains(rest, dp.Word) {
+			continue
+		}
+		rest = strings.Replace(rest, dp.Word, "", 1)
+		part = dp.Part
+		if !hasTime {
+			hour, minute, hasTime = dp.Hour, dp.Minute, true
+		}
+		break
+	}
+
+	var words []string
+	for _, w := range strings.Fields(rest) {
+		switch w {
+		case "at", "on", "the":
+			continue
+		}
+		words = append(words, w)
+	}
+	return hour, minute, hasTime, part, strings.Join(words, " "), true
+}
+
+func applyMeridiem(hour int, meridiem string) (int, bool) {
+	switch meridiem {
+	case "":
+		return hour, hour <= 23
+	case "am":
+		if hour < 1 || hour > 12 {
+			return 0, false
+		}
+		return hour % 12, true
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, false
+		}
+		return hour%12 + 12, true
+	}
+}
+
+// resolveDay returns midnight of the day named by s
+func resolveDay(s string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if s == "" {
+		return today, true
+	}
+
+	// Mongolian forms take case suffixes ("маргаашийн", "даваа гарагт")
+	s = strings.TrimSuffix(s, " гарагт")
+	s = strings.TrimSuffix(s, " гараг")
+	s = strings.TrimSuffix(s, " гаригт")
+	s = strings.TrimSuffix(s, " гариг")
+	s = strings.TrimSuffix(s, "ийн")
+
+	if offset, ok := relativeDays[s]; ok {
+		return today.AddDate(0, 0, offset), true
+	}
+
+	if m := inDaysRe.FindStringSubmatch(s); m != nil {
+		return addUnits(today, m[1], strings.HasPrefix(m[2], "week")), true
+	}
+	if m := daysLaterRe.FindStringSubmatch(s); m != nil {
+		return addUnits(today, m[1], strings.HasPrefix(m[2], "week")), true
+	}
+	if m := mnDaysRe.FindStringSubmatch(s); m != nil {
+		return addUnits(today, m[1], strings.HasPrefix(m[2], "долоо")), true
+	}
+
+	switch s {
+	case "next week", "ирэх долоо хоног", "дараа долоо хоног", "дараагийн долоо хоног":
+		return StartOfWeek(today).AddDate(0, 0, 7), true
+	}
+
+	strict := false
+	for _, prefix := range []string{"next ", "ирэх ", "дараагийн ", "дараа "} {
+		if strings.HasPrefix(s, prefix) {
+			s, strict = strings.TrimPrefix(s, prefix), true
+			break
+		}
+	}
+	for _, prefix := range []string{"this ", "coming ", "энэ "} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	if wd, ok := weekdays[s]; ok {
+		return nextWeekday(today, wd, strict), true
+	}
+	return time.Time{}, false
+}
+
+func addUnits(today time.Time, count string, weeks bool) time.Time {
+	n, _ := strconv.Atoi(count)
+	if weeks {
+		n *= 7
+	}
+	return today.AddDate(0, 0, n)
+}
+
+// nextWeekday returns the first wd on or after today, or strictly after today
+// when strict is set ("next monday" said on a Monday means a week later).
+func nextWeekday(today time.Time, wd time.Weekday, strict bool) time.Time {
+	diff := (int(wd) - int(today.Weekday()) + 7) % 7
+	if diff == 0 && strict {
+		diff = 7
+	}
+	return today.AddDate(0, 0, diff)
+}
+
+// StartOfWeek returns Monday midnight of the week containing t
+func StartOfWeek(t time.Time) time.Time {
+	diff := (int(t.Weekday()) + 6) % 7
+	day := t.AddDate(0, 0, -diff)
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, t.Location())
+}
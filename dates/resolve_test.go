@@ -0,0 +1,121 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+// resolveNow is Wednesday 2024-03-06 10:30 in Ulaanbaatar
+func resolveNow(t *testing.T) (time.Time, *time.Location) {
+	t.Helper()
+	loc, err := time.LoadLocation("Asia/Ulaanbaatar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return time.Date(2024, 3, 6, 10, 30, 0, 0, loc), loc
+}
+
+func TestResolve(t *testing.T) {
+	now, loc := resolveNow(t)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, loc)
+	}
+	tests := []struct {
+		input   string
+		want    time.Time
+		hasTime bool
+		part    string
+	}{
+		// Бүсгүй огноо, цаг нь loc-д
+		{"2024-03-10", at(10, 0, 0), false, ""},
+		{"2024/03/10", at(10, 0, 0), false, ""},
+		{"2024.03.10", at(10, 0, 0), false, ""},
+		{"2024-03-10 09:15", at(10, 9, 15), true, ""},
+		{"2024-03-10T09:15", at(10, 9, 15), true, ""},
+		{"2024/03/10 09:15", at(10, 9, 15), true, ""},
+		// Бүстэй утга өөрийн offset-оор
+		{"2024-03-10T09:15:00Z", at(10, 17, 15), true, ""},
+		{"2024-03-10T09:15:00+09:00", at(10, 8, 15), true, ""},
+		{"2024-03-10 09:15+08:00", at(10, 9, 15), true, ""},
+
+		{"now", at(6, 10, 30), true, ""},
+		{"today", at(6, 0, 0), false, ""},
+		{"Tonight", at(6, 18, 0), true, ""},
+		{"tonight at 9pm", at(6, 21, 0), true, ""},
+		{"tomorrow", at(7, 0, 0), false, ""},
+		{"yesterday", at(5, 0, 0), false, ""},
+		{"day after tomorrow", at(8, 0, 0), false, ""},
+		{"tomorrow morning", at(7, 9, 0), true, PartMorning},
+		{"tomorrow afternoon", at(7, 14, 0), true, PartAfternoon},
+		{"tomorrow evening", at(7, 18, 0), true, ""},
+		{"tomorrow at 3pm", at(7, 15, 0), true, ""},
+		{"tomorrow 9:30", at(7, 9, 30), true, ""},
+		{"friday 4:30 pm", at(8, 16, 30), true, ""},
+		{"monday", at(11, 0, 0), false, ""},
+		{"next Monday", at(11, 0, 0), false, ""},
+		{"wednesday", at(6, 0, 0), false, ""},
+		{"next wednesday", at(13, 0, 0), false, ""},
+		{"this friday", at(8, 0, 0), false, ""},
+		{"next monday afternoon", at(11, 14, 0), true, PartAfternoon},
+		{"next week", at(11, 0, 0), false, ""},
+		{"in 3 days", at(9, 0, 0), false, ""},
+		{"after 1 week", at(13, 0, 0), false, ""},
+		{"2 weeks from now", at(20, 0, 0), false, ""},
+		{"5 days later", at(11, 0, 0), false, ""},
+
+		{"одоо", at(6, 10, 30), true, ""},
+		{"өнөөдөр", at(6, 0, 0), false, ""},
+		{"өнөө орой", at(6, 18, 0), true, ""},
+		{"маргааш", at(7, 0, 0), false, ""},
+		{"өчигдөр", at(5, 0, 0), false, ""},
+		{"нөгөөдөр", at(8, 0, 0), false, ""},
+		{"маргааш өглөө", at(7, 9, 0), true, PartMorning},
+		{"маргаашийн үдээс хойш", at(7, 14, 0), true, PartAfternoon},
+		{"маргааш 10 цагт", at(7, 10, 0), true, ""},
+		{"маргааш 15:30", at(7, 15, 30), true, ""},
+		{"даваа гарагт", at(11, 0, 0), false, ""},
+		{"баасан гараг", at(8, 0, 0), false, ""},
+		{"ирэх даваа", at(11, 0, 0), false, ""},
+		{"ирэх лхагва", at(13, 0, 0), false, ""},
+		{"энэ баасан", at(8, 0, 0), false, ""},
+		{"ирэх долоо хоног", at(11, 0, 0), false, ""},
+		{"3 хоногийн дараа", at(9, 0, 0), false, ""},
+		{"2 долоо хоногийн дараа", at(20, 0, 0), false, ""},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.input, now, loc)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", tt.input, err)
+			continue
+		}
+		if !got.Time.Equal(tt.want) || got.HasTime != tt.hasTime || got.Part != tt.part {
+			t.Errorf("Resolve(%q) = %s hasTime=%v part=%q, want %s hasTime=%v part=%q",
+				tt.input, got.Time, got.HasTime, got.Part, tt.want, tt.hasTime, tt.part)
+		}
+		if got.Time.Location() != loc {
+			t.Errorf("Resolve(%q) location = %s, want %s", tt.input, got.Time.Location(), loc)
+		}
+	}
+}
+
+func TestResolveRejects(t *testing.T) {
+	now, loc := resolveNow(t)
+	for _, input := range []string{
+		"",
+		"   ",
+		"someday",
+		"next blursday",
+		"tomorrow 25:00",
+		"tomorrow 10:75",
+		"13pm",
+		"0am",
+		"маргааш 24 цагт",
+		"2024-13-01",
+		"2024-02-30",
+		"in three days",
+	} {
+		if got, err := Resolve(input, now, loc); err == nil {
+			t.Errorf("Resolve(%q) = %s, want an error", input, got.Time)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mcp-server/database"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Хүсэлтийн төлөвүүд
+const (
+	AbsenceStatusPending   = "pending"
+	AbsenceStatusApproved  = "approved"
+	AbsenceStatusRejected  = "rejected"
+	AbsenceStatusCancelled = "cancelled"
+)
+
+// DecideAbsence moves a pending absence to approved or rejected on behalf of
+// caller. Every way of deciding (tools, email links, chat buttons) goes
+// through here so the checks and notifications stay the same.
+func DecideAbsence(caller *Caller, absenceID uint, status, comment string) (*database.Absence, error) {
+	verb := "approve"
+	if status == AbsenceStatusRejected {
+		verb = "reject"
+	}
+
+	var absence database.Absence
+	if err := database.DB.First(&absence, absenceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "Absence not found")
+		}
+		return nil, err
+	}
+
+	// Хүсэлтэд оноогдсон удирдагч эсвэл бүх өгөгдөлд хандах эрхтэй хэрэглэгч шийднэ,
+	// оноогдоогүй бол өөрийн хүсэлтийг шийдэхгүй
+	if absence.EmployeeID == caller.ID && absence.LeaderID != caller.ID {
+		fmt.Println("Caller tried to decide own absence", caller.ID, absence.ID)
+		return nil, NewToolError(http.StatusForbidden, "You cannot %s your own absence", verb)
+	}
+	if !caller.CanDecideAbsence(&absence) {
+		fmt.Println("Caller is not the leader of absence", caller.ID, absence.ID)
+		return nil, NewToolError(http.StatusForbidden, "Only the assigned leader can %s this absence", verb)
+	}
+
+	// Аль хэдийн шийдэгдсэн эсэхийг шалгах
+	if absence.Status != AbsenceStatusPending {
+		fmt.Println("Absence already processed")
+		return nil, NewToolError(http.StatusBadRequest, "Absence already processed")
+	}
+
+	// Хавсралт шаарддаг шалтгаантай бол хавсралт байгаа эсэхийг шалгах
+	if status == AbsenceStatusApproved {
+		var reason database.AbsenceReason
+		if database.DB.Where("code = ?", absence.Reason).First(&reason).Error == nil &&
+			reason.RequiresAttachment && absenceAttachmentCount(absence.ID) == 0 {
+			fmt.Println("Attachment required for", absence.Reason)
+			return nil, NewToolError(http.StatusBadRequest, "Reason %s requires an attachment before approval", reason.Code)
+		}
+	}
+
+	now := time.Now()
+	absence.Status = status
+	absence.Comment = comment
+	absence.DecidedByID = &caller.ID
+	absence.DecidedAt = &now
+	absence.UpdatedAt = now
+
+	// Төлөв болон мэдэгдлийг нэг transaction-д
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Зэрэг шийдэхээс сэргийлж pending хэвээр байвал л шинэчилнэ
+		res := tx.Model(&database.Absence{}).
+			Where("id = ? AND status = ?", absence.ID, AbsenceStatusPending).
+			Updates(map[string]interface{}{
+				"status":           absence.Status,
+				"decision_comment": absence.Comment,
+				"decided_by_id":    caller.ID,
+				"decided_at":       now,
+				"updated_at":       now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return NewToolError(http.StatusConflict, "Absence already processed")
+		}
+		if err := revokeActionLinks(tx, absence.ID, now); err != nil {
+			return err
+		}
+		return notifyAbsenceDecided(tx, &absence, caller.User)
+	})
+	if err != nil {
+		fmt.Println("Failed to", verb, "absence", err)
+		return nil, err
+	}
+
+	fmt.Println("Absence", absence.ID, status, "by", caller.ID)
+	return &absence, nil
+}
+
+func decideAbsenceTool(caller *Caller, args map[string]interface{}, status string) (interface{}, error) {
+	absenceID, err := uintArg(args, "absence_id")
+	if err != nil {
+		return nil, err
+	}
+	comment := stringArg(args, "comment")
+	fmt.Println(status, absenceID, comment)
+
+	if _, err := DecideAbsence(caller, absenceID, status, comment); err != nil {
+		return nil, err
+	}
+	return "Absence " + status + " successfully", nil
+}
+
+// CancelAbsence cancels a pending or approved absence. The employee, the
+// creator, the assigned leader and HR may cancel; notifications include a
+// calendar CANCEL when the absence had been approved.
+func CancelAbsence(caller *Caller, absenceID uint, comment string) (*database.Absence, error) {
+	var absence database.Absence
+	if err := database.DB.First(&absence, absenceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "Absence not found")
+		}
+		return nil, err
+	}
+
+	if caller.ID != absence.EmployeeID && caller.ID != absence.CreatedUserID && !caller.CanDecideAbsence(&absence) {
+		fmt.Println("Caller cannot cancel absence", caller.ID, absence.ID)
+		return nil, NewToolError(http.StatusForbidden, "Only the employee, the assigned leader or HR can cancel this absence")
+	}
+
+	previous := absence.Status
+	if previous != AbsenceStatusPending && previous != AbsenceStatusApproved {
+		return nil, NewToolError(http.StatusBadRequest, "Absence is %s and cannot be cancelled", previous)
+	}
+
+	now := time.Now()
+	absence.Status = AbsenceStatusCancelled
+	absence.CancelComment = comment
+	absence.UpdatedAt = now
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&database.Absence{}).
+			Where("id = ? AND status = ?", absence.ID, previous).
+			Updates(map[string]interface{}{
+				"status":         absence.Status,
+				"cancel_comment": absence.CancelComment,
+				"updated_at":     now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return NewToolError(http.StatusConflict, "Absence was changed, try again")
+		}
+		if err := revokeActionLinks(tx, absence.ID, now); err != nil {
+			return err
+		}
+		return notifyAbsenceCancelled(tx, &absence, previous, caller.User)
+	})
+	if err != nil {
+		fmt.Println("Failed to cancel absence", err)
+		return nil, err
+	}
+
+	fmt.Println("Absence", absence.ID, "cancelled by", caller.ID)
+	return &absence, nil
+}
+
+func cancelAbsenceTool(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	absenceID, err := uintArg(args, "absence_id")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := CancelAbsence(caller, absenceID, stringArg(args, "comment")); err != nil {
+		return nil, err
+	}
+	return "Absence cancelled successfully", nil
+}
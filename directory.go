@@ -0,0 +1,276 @@
+package main
+
+import (
+	"mcp-server/audit"
+	"mcp-server/database"
+	"mcp-server/images"
+	"mcp-server/rbac"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PrivacyTier хэрэглэгчийн мэдээллийг хэр дэлгэрэнгүй харуулах түвшин
+type PrivacyTier string
+
+const (
+	TierPublic PrivacyTier = "public" // Байгууллагын лавлах
+	TierTeam   PrivacyTier = "team"   // Нэг багийнхан
+	TierHR     PrivacyTier = "hr"     // Хүний нөөц, бүтэн бүртгэл
+)
+
+// PublicUser бүх ажилтанд харагдах мэдээлэл
+type PublicUser struct {
+	ID              uint   `json:"id"`
+	Email           string `json:"email"`
+	FirstName       string `json:"first_name"`
+	LastName        string `json:"last_name"`
+	FirstNameMN     string `json:"first_name_mn"`
+	LastNameMN      string `json:"last_name_mn"`
+	NickName        string `json:"nick_name"`
+	Position        string `json:"position"`
+	TeamID          uint   `json:"team_id"`
+	ProfileImageURL string `json:"profile_image_url,omitempty"`
+}
+
+// ColleagueDetails багийнханд нэмж харагдах мэдээлэл
+type ColleagueDetails struct {
+	PhoneNumber string `json:"phone_number"`
+	Birthday    string `json:"birthday,omitempty"` // Зөвхөн сар-өдөр, MM-DD
+	Bio         string `json:"bio"`
+	Interests   string `json:"interests"`
+	IsFullTime  bool   `json:"is_full_time"`
+}
+
+// HRDetails include_sensitive үед хүний нөөцөд харагдах мэдээлэл
+type HRDetails struct {
+	BirthDate          string     `json:"birth_date,omitempty"`
+	Gender             string     `json:"gender"`
+	RegistrationNumber string     `json:"registration_number"` // Үргэлж далдлагдсан
+	IsActive           bool       `json:"is_active"`
+	IsTemprary         bool       `json:"is_temprary"`
+	RoleID             uint       `json:"role_id"`
+	TelegramChannel    string     `json:"telegram_channel"`
+	EmploymentDate     *time.Time `json:"employment_date,omitempty"`
+	DateOfNonTemprary  *time.Time `json:"date_of_non_temprary,omitempty"`
+	ResignationDate    *time.Time `json:"resignation_date,omitempty"`
+	LastLoginDate      *time.Time `json:"last_login_date,omitempty"`
+}
+
+// UserView хэрэглэгчийн хариу, түвшингээс хамаарч хэсгүүд нэмэгдэнэ
+type UserView struct {
+	Tier PrivacyTier `json:"privacy_tier"`
+	PublicUser
+	*ColleagueDetails
+	*HRDetails
+}
+
+// TeamView get_teams-ийн хариу
+type TeamView struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	IsActive    bool       `json:"is_active"`
+	Color       string     `json:"color"`
+	GroupMail   string     `json:"group_mail"`
+	LeaderID    uint       `json:"leader_id"`
+	Leader      *UserView  `json:"leader,omitempty"`
+	Members     []UserView `json:"members,omitempty"`
+}
+
+// MaskRegistrationNumber keeps the first two and last two characters of a
+// registration number, e.g. "УБ******12"
+func MaskRegistrationNumber(rd string) string {
+	runes := []rune(strings.TrimSpace(rd))
+	if len(runes) == 0 {
+		return ""
+	}
+	if len(runes) <= 4 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:2]) + strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-2:])
+}
+
+// privacyTier returns how much of user the caller may see by default
+func (c *Caller) privacyTier(user *database.User) PrivacyTier {
+	if c.Permissions.DataScope == rbac.ScopeAll {
+		return TierHR
+	}
+	if user.ID == c.ID || (user.TeamID != 0 && user.TeamID == c.TeamID) {
+		return TierTeam
+	}
+	for _, id := range c.Permissions.TeamIDs {
+		if id != 0 && id == user.TeamID {
+			return TierTeam
+		}
+	}
+	return TierPublic
+}
+
+// NewUserView builds the response for user. HR callers only get the full
+// record when they ask for it with sensitive, otherwise they see what a
+// colleague sees.
+func NewUserView(caller *Caller, user *database.User, sensitive bool) UserView {
+	view := UserView{
+		Tier: TierPublic,
+		PublicUser: PublicUser{
+			ID:          user.ID,
+			Email:       user.Email,
+			FirstName:   user.FirstName,
+			LastName:    user.LastName,
+			FirstNameMN: user.FirstNameMN,
+			LastNameMN:  user.LastNameMN,
+			NickName:    user.NickName,
+			Position:    user.Position,
+			TeamID:      user.TeamID,
+		},
+	}
+	if user.ProfileID != nil {
+		view.ProfileImageURL = AvatarURL(user, images.DefaultSize)
+	}
+
+	tier := caller.privacyTier(user)
+	if tier == TierPublic {
+		return view
+	}
+	view.Tier = TierTeam
+	view.ColleagueDetails = &ColleagueDetails{
+		PhoneNumber: user.PhoneNumber,
+		Bio:         user.Bio,
+		Interests:   user.Interests,
+		IsFullTime:  user.IsFullTime,
+	}
+	if !user.Birthday.IsZero() {
+		view.ColleagueDetails.Birthday = user.Birthday.Format("01-02")
+	}
+
+	if tier != TierHR || !sensitive {
+		return view
+	}
+	view.Tier = TierHR
+	view.HRDetails = &HRDetails{
+		Gender:             user.Gender,
+		RegistrationNumber: maskedRegistrationNumber(user),
+		IsActive:           user.IsActive,
+		IsTemprary:         user.IsTemprary,
+		RoleID:             user.RoleID,
+		TelegramChannel:    user.TelegramChannel,
+		EmploymentDate:     optionalTime(user.EmploymentDate),
+		DateOfNonTemprary:  optionalTime(user.DateOfNonTemprary),
+		ResignationDate:    optionalTime(user.ResignationDate),
+		LastLoginDate:      optionalTime(user.LastLoginDate),
+	}
+	if !user.Birthday.IsZero() {
+		view.HRDetails.BirthDate = user.Birthday.Format("2006-01-02")
+	}
+	return view
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// sensitiveArg reads include_sensitive and checks the caller may use it
+func sensitiveArg(caller *Caller, args map[string]interface{}) (bool, error) {
+	if sensitive, _ := boolArg(args, "include_sensitive"); !sensitive {
+		return false, nil
+	}
+	if caller.Permissions.DataScope != rbac.ScopeAll {
+		return false, NewToolError(http.StatusForbidden, "include_sensitive is only available to HR")
+	}
+	return true, nil
+}
+
+// auditSensitive records that the caller saw full records of users. The
+// records must not be returned when it fails.
+func auditSensitive(caller *Caller, tool string, users []*database.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return audit.Record(caller.ID, audit.ActionViewSensitive, "user", ids, map[string]string{"tool": tool})
+}
+
+func getUsers(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	sensitive, err := sensitiveArg(caller, args)
+	if err != nil {
+		return nil, err
+	}
+
+	query := database.DB.Order("id")
+	if id, ok := floatArg(args, "team_id"); ok && id > 0 {
+		query = query.Where("team_id = ?", uint(id))
+	}
+	var users []*database.User
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	views := make([]UserView, 0, len(users))
+	for _, u := range users {
+		views = append(views, NewUserView(caller, u, sensitive))
+	}
+	// Аудитын бичлэггүйгээр хувийн мэдээлэл буцаахгүй
+	if sensitive {
+		if err := auditSensitive(caller, "get_users", users); err != nil {
+			return nil, err
+		}
+	}
+	return views, nil
+}
+
+func getTeams(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	sensitive, err := sensitiveArg(caller, args)
+	if err != nil {
+		return nil, err
+	}
+	withMembers, _ := boolArg(args, "include_members")
+
+	query := database.DB.Preload("Leader").Order("id")
+	if withMembers {
+		query = query.Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+	}
+	var teams []database.Team
+	if err := query.Find(&teams).Error; err != nil {
+		return nil, err
+	}
+
+	var seen []*database.User
+	views := make([]TeamView, 0, len(teams))
+	for _, t := range teams {
+		view := TeamView{
+			ID:          t.ID,
+			Name:        t.Name,
+			Description: t.Description,
+			IsActive:    t.IsActive,
+			Color:       t.Color,
+			GroupMail:   t.GroupMail,
+			LeaderID:    t.LeaderID,
+		}
+		if t.Leader != nil {
+			leader := NewUserView(caller, t.Leader, sensitive)
+			view.Leader = &leader
+			seen = append(seen, t.Leader)
+		}
+		for _, m := range t.Members {
+			view.Members = append(view.Members, NewUserView(caller, m, sensitive))
+			seen = append(seen, m)
+		}
+		views = append(views, view)
+	}
+	// Аудитын бичлэггүйгээр хувийн мэдээлэл буцаахгүй
+	if sensitive {
+		if err := auditSensitive(caller, "get_teams", seen); err != nil {
+			return nil, err
+		}
+	}
+	return views, nil
+}
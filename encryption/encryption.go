@@ -0,0 +1,196 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mcp-server/secrets"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrNoKey     = errors.New("encryption key is not configured")
+	ErrMalformed = errors.New("malformed encrypted value")
+	ErrLegacy    = errors.New("encrypted value has no key version")
+)
+
+// Keyring AES-GCM түлхүүрүүд хувилбараар, шинэ утгыг Current-ээр шифрлэнэ
+type Keyring struct {
+	Current int
+	keys    map[int]cipher.AEAD
+}
+
+var keyring = &Keyring{keys: map[int]cipher.AEAD{}}
+
+// Configure loads the keys. CIPHER_KEY is key version CIPHER_KEY_VERSION
+// (default 1); older keys kept for decryption during rotation are listed in
+// CIPHER_KEYS as "1:<key>,2:<key>". A key is 16, 24 or 32 raw bytes or their
+// base64 form.
+func Configure() error {
+	version := viper.GetInt("CIPHER_KEY_VERSION")
+	if version == 0 {
+		version = 1
+	}
+	ring := &Keyring{Current: version, keys: map[int]cipher.AEAD{}}
+
+	for _, entry := range strings.Split(secrets.Get("CIPHER_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		v, key, ok := strings.Cut(entry, ":")
+		n, err := strconv.Atoi(strings.TrimPrefix(v, "v"))
+		if !ok || err != nil || n <= 0 {
+			return fmt.Errorf("CIPHER_KEYS entry %q must look like <version>:<key>", v)
+		}
+		if err := ring.Add(n, key); err != nil {
+			return fmt.Errorf("cipher key v%d: %w", n, err)
+		}
+	}
+	if key := secrets.Get("CIPHER_KEY"); key != "" {
+		if err := ring.Add(version, key); err != nil {
+			return fmt.Errorf("CIPHER_KEY: %w", err)
+		}
+	}
+	if _, ok := ring.keys[version]; !ok {
+		return fmt.Errorf("%w: no key for current version v%d", ErrNoKey, version)
+	}
+	keyring = ring
+	return nil
+}
+
+// Add registers a key under version
+func (k *Keyring) Add(version int, key string) error {
+	raw, err := decodeKey(key)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	k.keys[version] = aead
+	return nil
+}
+
+func decodeKey(key string) ([]byte, error) {
+	key = strings.TrimSpace(key)
+	switch len(key) {
+	case 16, 24, 32:
+		return []byte(key), nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.New("key must be 16, 24 or 32 bytes or base64 of them")
+	}
+	switch len(raw) {
+	case 16, 24, 32:
+		return raw, nil
+	}
+	return nil, fmt.Errorf("decoded key is %d bytes, expected 16, 24 or 32", len(raw))
+}
+
+// Versions returns the loaded key versions
+func (k *Keyring) Versions() []int {
+	var versions []int
+	for v := range k.keys {
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Encrypt seals plaintext with the current key as "v<N>:<base64(nonce|ciphertext)>"
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	aead, ok := k.keys[k.Current]
+	if !ok {
+		return "", ErrNoKey
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return fmt.Sprintf("v%d:%s", k.Current, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// Decrypt opens a value produced by Encrypt with the key it names
+func (k *Keyring) Decrypt(value string) (string, error) {
+	version, payload, err := split(value)
+	if err != nil {
+		return "", err
+	}
+	aead, ok := k.keys[version]
+	if !ok {
+		return "", fmt.Errorf("%w: v%d", ErrNoKey, version)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt v%d: %w", version, err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether value was sealed with an older key
+func (k *Keyring) NeedsRotation(value string) bool {
+	version, _, err := split(value)
+	return err == nil && version != k.Current
+}
+
+// Rotate re-encrypts value with the current key
+func (k *Keyring) Rotate(value string) (string, error) {
+	plaintext, err := k.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(plaintext)
+}
+
+// Version returns the key version of an encrypted value
+func Version(value string) (int, error) {
+	version, _, err := split(value)
+	return version, err
+}
+
+func split(value string) (int, string, error) {
+	if !strings.HasPrefix(value, "v") {
+		return 0, "", ErrLegacy
+	}
+	prefix, payload, ok := strings.Cut(value[1:], ":")
+	if !ok {
+		return 0, "", ErrLegacy
+	}
+	version, err := strconv.Atoi(prefix)
+	if err != nil || version <= 0 {
+		return 0, "", ErrMalformed
+	}
+	return version, payload, nil
+}
+
+// Default returns the keyring loaded by Configure
+func Default() *Keyring {
+	return keyring
+}
+
+func Encrypt(plaintext string) (string, error) {
+	return keyring.Encrypt(plaintext)
+}
+
+func Decrypt(value string) (string, error) {
+	return keyring.Decrypt(value)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mcp-server/storage"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// FileDownloadHandler GET /files/{key...}, storage.SignedURL-ээр үүсгэсэн холбоос
+func FileDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if err := storage.VerifySignedURL(key, r.URL.Query()); err != nil {
+		http.Error(w, "Invalid or expired link", http.StatusForbidden)
+		return
+	}
+	serveStoredFile(w, r, key, mime.TypeByExtension(path.Ext(key)), "")
+}
+
+// serveStoredFile sends key from storage. The checksum is verified before the
+// first byte is written so a corrupted file gets a 500, not a 200 with bad data.
+func serveStoredFile(w http.ResponseWriter, r *http.Request, key, contentType, fileName string) {
+	data, err := storage.ReadAll(r.Context(), key)
+	if err != nil {
+		fmt.Println("Failed to read stored file", key, err)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if fileName != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	}
+	if _, err := w.Write(data); err != nil {
+		fmt.Println("Failed to send file", key, err)
+	}
+}
@@ -0,0 +1,8 @@
+package files
+
+import "embed"
+
+// EmailTemplates нь бинарт суулгасан имэйлийн загварууд, MAIL_TEMPLATE_DIR-ээр дарж болно
+//
+//go:embed email-template
+var EmailTemplates embed.FS
@@ -2,7 +2,9 @@ package main
 
 import (
 	"mcp-server/database"
+	"strings"
 
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
 
@@ -34,3 +36,12 @@ func GetUserLeader(db *gorm.DB, user database.User, role database.Role) (*databa
 	}
 	return nil, nil
 }
+
+// PublicURL returns the absolute URL of a path served by this server
+func PublicURL(path string) string {
+	base := viper.GetString("PUBLIC_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return strings.TrimRight(base, "/") + path
+}
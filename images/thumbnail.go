@@ -0,0 +1,164 @@
+package images
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Зөвшөөрөгдсөн хэмжээнүүд, кэш хязгааргүй өсөхөөс сэргийлнэ
+var Sizes = []int{32, 64, 128, 256, 512}
+
+const DefaultSize = 128
+
+// MaxPixels нь задлах зургийн дээд хэмжээ, жижиг файлд том зураг нуусан
+// decompression bomb-оос сэргийлнэ
+const MaxPixels = 40_000_000
+
+var (
+	ErrUnsupported = errors.New("images: unsupported image format")
+	ErrTooLarge    = errors.New("images: image dimensions are too large")
+)
+
+// NearestSize snaps the requested size to the closest allowed size not smaller than it
+func NearestSize(size int) int {
+	for _, s := range Sizes {
+		if size <= s {
+			return s
+		}
+	}
+	return Sizes[len(Sizes)-1]
+}
+
+// Thumbnail center-crops src to a square and scales it to size pixels.
+// Images with transparency are encoded as PNG, others as JPEG.
+// The dimensions are checked from the header before the pixels are decoded.
+func Thumbnail(src []byte, size int) ([]byte, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(src))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+	img, format, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	if side == 0 {
+		return nil, "", ErrUnsupported
+	}
+	crop := image.Rect(0, 0, side, side)
+	square := image.NewRGBA(crop)
+	offset := image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2)
+	draw.Draw(square, crop, img, offset, draw.Src)
+
+	thumb := scale(square, size)
+
+	var out bytes.Buffer
+	if format != "jpeg" && !thumb.Opaque() {
+		err = png.Encode(&out, thumb)
+		return out.Bytes(), "image/png", err
+	}
+	err = jpeg.Encode(&out, thumb, &jpeg.Options{Quality: 85})
+	return out.Bytes(), "image/jpeg", err
+}
+
+// scale resizes a square RGBA image with box averaging
+func scale(src *image.RGBA, size int) *image.RGBA {
+	srcSize := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := span(y, size, srcSize)
+		for x := 0; x < size; x++ {
+			x0, x1 := span(x, size, srcSize)
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := src.PixOffset(sx, sy)
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span returns the source pixel range covered by destination pixel i
+func span(i, dstSize, srcSize int) (int, int) {
+	start := i * srcSize / dstSize
+	end := (i + 1) * srcSize / dstSize
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// Cache дискэн дээрх thumbnail-ийн кэш
+type Cache struct {
+	Dir string
+}
+
+func (c Cache) path(source string, size int, ext string) string {
+	sum := sha256.Sum256([]byte(source))
+	name := hex.EncodeToString(sum[:]) + "_" + strconv.Itoa(size) + ext
+	return filepath.Join(c.Dir, name[:2], name)
+}
+
+// Get returns a cached thumbnail of source, generating it with load on a miss
+func (c Cache) Get(source string, size int, load func() ([]byte, error)) ([]byte, string, error) {
+	for ext, contentType := range map[string]string{".jpg": "image/jpeg", ".png": "image/png"} {
+		if data, err := os.ReadFile(c.path(source, size, ext)); err == nil {
+			return data, contentType, nil
+		}
+	}
+
+	src, err := load()
+	if err != nil {
+		return nil, "", err
+	}
+	data, contentType, err := Thumbnail(src, size)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ext := ".jpg"
+	if contentType == "image/png" {
+		ext = ".png"
+	}
+	p := c.path(source, size, ext)
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err == nil {
+		tmp := p + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o640); err == nil {
+			os.Rename(tmp, p)
+		}
+	} else {
+		fmt.Println("Thumbnail cache error", err)
+	}
+	return data, contentType, nil
+}
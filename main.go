@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"mcp-server/auth"
 	"mcp-server/database"
+	"mcp-server/dates"
+	"mcp-server/encryption"
+	"mcp-server/outbox"
+	"mcp-server/secrets"
+	"mcp-server/smtp"
+	"mcp-server/storage"
+	"mcp-server/telegram"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"gorm.io/gorm"
 )
 
 type FunctionCall struct {
@@ -21,14 +33,93 @@ type FunctionResponse struct {
 }
 
 func main() {
+	// .env нь зөвхөн локал хөгжүүлэлтэд, image болон сервер дээр тохиргоо орчны хувьсагчаас ирнэ
 	viper.SetConfigFile(".env")
-	if err := viper.ReadInConfig(); err != nil {
+	viper.AutomaticEnv()
+	if err := viper.ReadInConfig(); err != nil && !os.IsNotExist(err) {
 		log.Fatalf("Error on load config: %s\n", err)
 	}
 
+	if err := secrets.Configure(); err != nil {
+		log.Fatalf("Error on load secrets: %s\n", err)
+	}
+	if err := secrets.Check(requiredSecrets()); err != nil {
+		log.Fatalf("%s\n", err)
+	}
+
+	if err := dates.Configure(viper.GetString("DB_TIMEZONE")); err != nil {
+		log.Fatalf("Error on load timezone: %s\n", err)
+	}
+
+	if err := dates.ConfigureCalendar(
+		viper.GetString("WORK_DAY_START"),
+		viper.GetString("WORK_LUNCH_START"),
+		viper.GetString("WORK_LUNCH_END"),
+		viper.GetString("WORK_DAY_END"),
+		viper.GetString("WORK_WEEKEND"),
+		viper.GetString("WORK_HOLIDAYS"),
+	); err != nil {
+		log.Fatalf("Error on load working calendar: %s\n", err)
+	}
+
+	if err := encryption.Configure(); err != nil {
+		log.Fatalf("Error on load cipher keys: %s\n", err)
+	}
+
 	database.CreateClient()
+	database.AutoMigrate()
+
+	if err := smtp.ConfigureTemplates(); err != nil {
+		log.Fatalf("Error on load email templates: %s\n", err)
+	}
+	if _, err := smtp.Configure(); err != nil {
+		log.Fatalf("Error on init mailer: %s\n", err)
+	}
+	if _, err := smtp.ConfigureDKIM(); err != nil {
+		log.Fatalf("Error on init DKIM: %s\n", err)
+	}
+	if _, err := telegram.Configure(); err != nil {
+		log.Fatalf("Error on init Telegram: %s\n", err)
+	}
+	outbox.Register(outbox.ChannelTelegram, telegram.Deliver)
+
+	if _, err := storage.CreateClient(); err != nil {
+		log.Fatalf("Error on init storage: %s\n", err)
+	}
+
+	if err := auth.Configure(); err != nil {
+		log.Fatalf("Error on load auth config: %s\n", err)
+	}
+
+	if err := ConfigurePolicy(); err != nil {
+		log.Fatalf("Error on load RBAC policy: %s\n", err)
+	}
+
+	// go run . <command> ... удирдлагын командууд
+	if len(os.Args) > 1 {
+		if err := RunCommand(os.Args[1:]); err != nil {
+			log.Fatalf("%s: %s\n", os.Args[1], err)
+		}
+		return
+	}
+
+	http.HandleFunc("GET /.well-known/oauth-protected-resource", auth.ProtectedResourceHandler)
+	http.Handle("/call-function", auth.Middleware(http.HandlerFunc(MCPHandler)))
+	http.Handle("GET /call-function", auth.Middleware(http.HandlerFunc(SessionEventsHandler)))
+	http.Handle("DELETE /call-function", auth.Middleware(http.HandlerFunc(SessionDeleteHandler)))
+	http.Handle("GET /admin/email-preview", auth.Middleware(http.HandlerFunc(EmailPreviewHandler)))
+	http.Handle("GET /admin/email-preview/{template}", auth.Middleware(http.HandlerFunc(EmailPreviewHandler)))
+	http.Handle("POST /admin/email-preview/{template}", auth.Middleware(http.HandlerFunc(EmailPreviewHandler)))
+	http.Handle("POST /absences/{id}/attachments", auth.Middleware(http.HandlerFunc(AttachmentUploadHandler)))
+	http.Handle("GET /absences/{id}/attachments/{file_id}", auth.Middleware(http.HandlerFunc(AttachmentDownloadHandler)))
+	http.HandleFunc("GET /absence-actions/{token}", AbsenceActionHandler)
+	http.HandleFunc("POST /absence-actions/{token}", AbsenceActionHandler)
+	http.HandleFunc("POST /telegram/webhook", TelegramWebhookHandler)
+	http.HandleFunc("GET /files/{key...}", FileDownloadHandler)
+	http.HandleFunc("GET /users/{id}/avatar", AvatarHandler)
+	go WatchSessions()
+	go outbox.Run(context.Background(), smtp.Default)
 
-	http.HandleFunc("/call-function", MCPHandler)
 	log.Println("MCP Server listening on :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
@@ -40,41 +131,75 @@ func MCPHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Хэрэгслүүд args доторх имэйлээр бус, нэвтэрсэн хэрэглэгчийн нэрийн өмнөөс ажиллана
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	caller, err := NewCaller(identity)
+	if err != nil {
+		fmt.Println("Failed to resolve permissions", err)
+		http.Error(w, "Failed to resolve permissions", http.StatusInternalServerError)
+		return
+	}
+
+	session, err := resolveSession(w, r, caller, call.Function)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+	if session != nil && call.Function != "tools/list" {
+		syncSessionTools(session, caller)
+	}
+
+	// Эрхийн бодлогоор хэрэгслийг хязгаарлах
+	if call.Function != "tools/list" && !caller.Permissions.CanUse(call.Function) {
+		fmt.Println(call.Function, "denied for roles", caller.Permissions.Roles)
+		http.Error(w, "Your role is not allowed to use "+call.Function, http.StatusForbidden)
+		return
+	}
+	if scope := toolScopes[call.Function]; scope != "" && !identity.HasScope(scope) {
+		fmt.Println(call.Function, "denied, missing scope", scope)
+		auth.InsufficientScope(w, scope)
+		return
+	}
+
 	var result interface{}
 	switch call.Function {
+	case "tools/list":
+		if session != nil {
+			session.SetToolsHash(toolsFingerprint(caller))
+		}
+		result = map[string]interface{}{"tools": ListTools(caller)}
+
 	case "get_teams":
 		fmt.Println("get_teams")
-		var teams []database.Team
-		database.DB.Find(&teams)
-		result = teams
+		result, err = getTeams(caller, call.Args)
 
 	case "get_users":
-		type UserResponse struct {
-			ID        uint   `json:"id"`
-			FirstName string `json:"first_name"`
-			LastName  string `json:"last_name"`
-			Email     string `json:"email"`
-		}
-
 		fmt.Println("get_users")
-		var users []UserResponse
-		database.DB.Model(&database.User{}).Select("id, first_name, last_name, email").Find(&users)
-		result = users
+		result, err = getUsers(caller, call.Args)
 
 	case "create_absence_request":
-		userEmail := call.Args["user_email"].(string)
+		userEmail, _ := call.Args["user_email"].(string)
 		startDateStr := call.Args["start_date"].(string)
-		endDateStr := call.Args["end_date"].(string)
-		reason := call.Args["reason"].(string)
-		inActiveHours := call.Args["in_active_hours"].(float64)
+		endDateStr, _ := call.Args["end_date"].(string)
+		reasonInput := call.Args["reason"].(string)
+		kind, _ := call.Args["kind"].(string)
 		description := call.Args["description"].(string)
 
-		fmt.Println(userEmail, startDateStr, endDateStr, reason, description)
+		fmt.Println(userEmail, startDateStr, endDateStr, reasonInput, description)
 
-		var user database.User
-		if err := database.DB.Preload("Team").Preload("Role").Where("email = ?", userEmail).First(&user).Error; err != nil {
-			fmt.Println("User not found", err)
-			http.Error(w, "User not found", http.StatusNotFound)
+		if userEmail != "" && !strings.EqualFold(userEmail, caller.Email) {
+			fmt.Println("user_email does not match caller", userEmail, caller.Email)
+			http.Error(w, "user_email must be the authenticated user's email", http.StatusForbidden)
+			return
+		}
+		user := *caller.User
+		if user.Role == nil {
+			fmt.Println("User has no role", user.ID)
+			http.Error(w, "User has no role", http.StatusBadRequest)
 			return
 		}
 
@@ -85,20 +210,52 @@ func MCPHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		startDate, err := time.Parse("2006-01-02T15:04:05.000Z", startDateStr)
+		start, err := dates.Parse(startDateStr)
 		if err != nil {
-			// Try alternative format
-			startDate, err = time.Parse("2006-01-02", startDateStr)
-			if err != nil {
-				fmt.Println("Invalid start_date format")
-				http.Error(w, "Invalid start_date format", http.StatusBadRequest)
+			fmt.Println("Invalid start_date", err)
+			http.Error(w, "Invalid start_date: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		startDate := start.Time
+
+		// end_date байхгүй бол эхлэх өдөртэй ижил
+		end := start
+		if endDateStr != "" {
+			if end, err = dates.Parse(endDateStr); err != nil {
+				fmt.Println("Invalid end_date", err)
+				http.Error(w, "Invalid end_date: "+err.Error(), http.StatusBadRequest)
 				return
 			}
 		}
+		if end.Time.Before(startDate) {
+			fmt.Println("end_date is before start_date")
+			http.Error(w, "end_date must not be before start_date", http.StatusBadRequest)
+			return
+		}
+
+		// Ажлын цагийг клиентээс авахгүй, хуанлиас тооцно
+		period, err := NewAbsencePeriod(kind, start, end)
+		if err != nil {
+			fmt.Println("Invalid absence period", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		startDate = period.Start
+
+		// Шалтгааныг каталогоос шалгаж, бодлогыг хэрэгжүүлэх
+		reason, err := FindAbsenceReason(reasonInput)
+		if err == nil {
+			err = CheckReasonPolicy(reason, period)
+		}
+		if err != nil {
+			fmt.Println("Invalid reason", err)
+			writeToolError(w, err)
+			return
+		}
 
 		// Тохирох интервалыг олох
 		var interval database.TimeInterval
-		if err := database.DB.Where("end_date::date >= date(?)", startDateStr).First(&interval).Error; err != nil {
+		if err := database.DB.Where("end_date >= ?", dates.StartOfDay(startDate)).Order("end_date").First(&interval).Error; err != nil {
 			fmt.Println("No matching interval found for start_date:", startDate)
 			http.Error(w, "No matching interval found for the given start date", http.StatusBadRequest)
 			return
@@ -106,147 +263,134 @@ func MCPHandler(w http.ResponseWriter, r *http.Request) {
 
 		instance := database.Absence{
 			CreatedUserID: user.ID,
-			StartDate:     startDate,
-			Reason:        reason,
+			StartDate:     period.Start,
+			EndDate:       period.End,
+			Kind:          period.Kind,
+			Reason:        reason.Code,
 			EmployeeID:    user.ID,
-			InActiveHours: inActiveHours,
-			Status:        "pending",
+			InActiveHours: period.Hours,
+			Status:        AbsenceStatusPending,
 			LeaderID:      leader.ID,
 			IntervalID:    interval.ID,
 			Description:   description,
 		}
 
-		if err := database.DB.Create(&instance).Error; err != nil {
-			fmt.Println("Failed to create absence request")
+		// Хүсэлт болон удирдагчид очих имэйлийг нэг transaction-д хадгална,
+		// имэйлийг outbox worker дараа нь илгээнэ
+		if err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&instance).Error; err != nil {
+				return err
+			}
+			return notifyAbsenceRequested(tx, &instance, &user, leader)
+		}); err != nil {
+			fmt.Println("Failed to create absence request", err)
 			http.Error(w, "Failed to create absence request", http.StatusInternalServerError)
 			return
 		}
 
-		// if err := smtp.CreateClient().Send(smtp.EmailInput{
-		// 	Template: "request",
-		// 	Email:    "tuvshinjargal@fibo.cloud",
-		// 	MultiBcc: []string{"tuvshinjargal@fibo.cloud"},
-		// }, map[string]interface{}{
-		// 	"employee_email": "tuvshinjargal@fibo.cloud",
-		// 	"start_date":     startDateStr,
-		// 	"end_date":       endDateStr,
-		// 	"reason":         reason,
-		// }); err != nil {
-		// 	fmt.Println("Failed to send email", err)
-		// 	http.Error(w, "Failed to send email", http.StatusInternalServerError)
-		// 	return
-		// }
-
 		// TEAM INTEGRION -> FIBO CLOUD chat ym yvuulna, goy bainadaa
 
 		fmt.Printf("Absence request created successfully with ID: %d\n", instance.ID)
 		result = map[string]interface{}{
-			"message":    "Absence request created successfully",
-			"absence_id": instance.ID,
-			"status":     instance.Status,
+			"message":         "Absence request created successfully",
+			"absence_id":      instance.ID,
+			"status":          instance.Status,
+			"reason":          instance.Reason,
+			"kind":            instance.Kind,
+			"start_date":      period.Start.Format(time.RFC3339),
+			"end_date":        period.End.Format(time.RFC3339),
+			"in_active_hours": period.Hours,
 		}
 		
 	case "approve_absence":
-		absenceID := uint(call.Args["absence_id"].(float64))
-		comment := ""
-		if call.Args["comment"] != nil {
-			comment = call.Args["comment"].(string)
-		}
+		result, err = decideAbsenceTool(caller, call.Args, AbsenceStatusApproved)
+
+	case "reject_absence":
+		result, err = decideAbsenceTool(caller, call.Args, AbsenceStatusRejected)
+
+	case "cancel_absence":
+		result, err = cancelAbsenceTool(caller, call.Args)
 
-		fmt.Println("approve_absence", absenceID, comment)
+	case "get_time_intervals":
+		startDateStr := call.Args["start_date"].(string)
+		
+		fmt.Println("get_time_intervals", startDateStr)
 
-		var absence database.Absence
-		if err := database.DB.First(&absence, absenceID).Error; err != nil {
-			fmt.Println("Absence not found", err)
-			http.Error(w, "Absence not found", http.StatusNotFound)
+		start, err := dates.Parse(startDateStr)
+		if err != nil {
+			fmt.Println("Invalid start_date", err)
+			http.Error(w, "Invalid start_date: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Аль хэдийн шийдэгдсэн эсэхийг шалгах
-		if absence.Status != "pending" {
-			fmt.Println("Absence already processed")
-			http.Error(w, "Absence already processed", http.StatusBadRequest)
+		var intervals []database.TimeInterval
+		if err := database.DB.Where("end_date >= ?", dates.StartOfDay(start.Time)).Order("end_date").Find(&intervals).Error; err != nil {
+			fmt.Println("Failed to get time intervals", err)
+			http.Error(w, "Failed to get time intervals", http.StatusInternalServerError)
 			return
 		}
+		
+		result = intervals
 
-		// Status-г approved болгох
-		absence.Status = "approved"
-		absence.UpdatedAt = time.Now()
+	case "get_absence_report":
+		result, err = getAbsenceReport(caller, call.Args)
 
-		if comment != "" {
-			fmt.Println("Approval comment:", comment)
-		}
+	case "set_user_role":
+		result, err = setUserRole(caller, call.Args)
 
-		// Database-д хадгалах
-		if err := database.DB.Save(&absence).Error; err != nil {
-			fmt.Println("Failed to approve absence", err)
-			http.Error(w, "Failed to approve absence", http.StatusInternalServerError)
-			return
-		}
+	case "set_notification_language":
+		result, err = setNotificationLanguage(caller, call.Args)
 
-		fmt.Println("Absence approved successfully")
-		result = "Absence approved successfully"
+	case "preview_email":
+		result, err = previewEmail(call.Args)
 
-	case "reject_absence":
-		absenceID := uint(call.Args["absence_id"].(float64))
-		comment := ""
-		if call.Args["comment"] != nil {
-			comment = call.Args["comment"].(string)
-		}
+	case "list_outbox":
+		result, err = listOutbox(call.Args)
 
-		fmt.Println("reject_absence", absenceID, comment)
+	case "retry_outbox_message":
+		result, err = retryOutboxMessage(call.Args)
 
-		var absence database.Absence
-		if err := database.DB.First(&absence, absenceID).Error; err != nil {
-			fmt.Println("Absence not found", err)
-			http.Error(w, "Absence not found", http.StatusNotFound)
-			return
-		}
+	case "reveal_registration_number":
+		result, err = revealRegistrationNumber(caller, call.Args)
 
-		// Аль хэдийн шийдэгдсэн эсэхийг шалгах
-		if absence.Status != "pending" {
-			fmt.Println("Absence already processed")
-			http.Error(w, "Absence already processed", http.StatusBadRequest)
-			return
-		}
+	case "list_absences":
+		result, err = listAbsences(caller, call.Args)
 
-		// Status-г rejected болгох
-		absence.Status = "rejected"
-		absence.UpdatedAt = time.Now()
+	case "get_effective_permissions":
+		result, err = getEffectivePermissions(caller, call.Args)
 
-		if comment != "" {
-			fmt.Println("Rejection comment:", comment)
-		}
+	case "get_user_profile":
+		result, err = getUserProfile(caller, call.Args)
 
-		// Database-д хадгалах
-		if err := database.DB.Save(&absence).Error; err != nil {
-			fmt.Println("Failed to reject absence", err)
-			http.Error(w, "Failed to reject absence", http.StatusInternalServerError)
-			return
-		}
+	case "upload_absence_attachment":
+		result, err = uploadAbsenceAttachment(caller, call.Args)
 
-		fmt.Println("Absence rejected successfully")
-		result = "Absence rejected successfully"
+	case "list_absence_attachments":
+		result, err = listAbsenceAttachments(caller, call.Args)
 
-	case "get_time_intervals":
-		startDateStr := call.Args["start_date"].(string)
-		
-		fmt.Println("get_time_intervals", startDateStr)
+	case "download_absence_attachment":
+		result, err = downloadAbsenceAttachment(caller, call.Args)
 
-		var intervals []database.TimeInterval
-		if err := database.DB.Where("end_date::date >= date(?)", startDateStr).Find(&intervals).Error; err != nil {
-			fmt.Println("Failed to get time intervals", err)
-			http.Error(w, "Failed to get time intervals", http.StatusInternalServerError)
-			return
-		}
-		
-		result = intervals
+	case "list_absence_reasons":
+		result, err = listAbsenceReasons(call.Args)
+
+	case "create_absence_reason":
+		result, err = createAbsenceReason(call.Args)
+
+	case "update_absence_reason":
+		result, err = updateAbsenceReason(call.Args)
 
 	default:
 		http.Error(w, "Unknown function", http.StatusNotFound)
 		return
 	}
 
+	if err != nil {
+		fmt.Println(call.Function, "failed:", err)
+		writeToolError(w, err)
+		return
+	}
+
 	json.NewEncoder(w).Encode(FunctionResponse{Result: result})
 }
 
@@ -254,4 +398,4 @@ func MCPHandler(w http.ResponseWriter, r *http.Request) {
 type AbsenceApprovalParam struct {
 	AbsenceID uint   `json:"absence_id" binding:"required"`
 	Comment   string `json:"comment,omitempty"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"mcp-server/database"
+	"mcp-server/dates"
+	"mcp-server/outbox"
+	"mcp-server/smtp"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Имэйлийн загварууд, files/email-template/<name>.html
+const (
+	templateAbsenceRequest   = "request"
+	templateAbsenceApproved  = "approved"
+	templateAbsenceRejected  = "rejected"
+	templateAbsenceCancelled = "cancelled"
+)
+
+func fullName(user *database.User) string {
+	if user == nil {
+		return ""
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		return user.Email
+	}
+	return name
+}
+
+// teamGroupMail returns the group mail of the user's team, empty when there is none
+func teamGroupMail(user *database.User) string {
+	if user == nil || user.TeamID == 0 {
+		return ""
+	}
+	team := user.Team
+	if team == nil {
+		team = &database.Team{}
+		if err := database.DB.Select("id, group_mail").First(team, user.TeamID).Error; err != nil {
+			return ""
+		}
+	}
+	return team.GroupMail
+}
+
+// teamCc returns the team's group mail when NOTIFY_CC_GROUP_MAIL is on
+func teamCc(user *database.User) []string {
+	if !viper.GetBool("NOTIFY_CC_GROUP_MAIL") {
+		return nil
+	}
+	if mail := teamGroupMail(user); mail != "" {
+		return []string{mail}
+	}
+	return nil
+}
+
+// calendarInvites reports whether approvals carry a calendar invite,
+// NOTIFY_CALENDAR_INVITES, on by default
+func calendarInvites() bool {
+	return !viper.IsSet("NOTIFY_CALENDAR_INVITES") || viper.GetBool("NOTIFY_CALENDAR_INVITES")
+}
+
+// calendarCc adds the team's group mail so the absence shows in the team calendar
+func calendarCc(employee *database.User) []string {
+	cc := teamCc(employee)
+	if mail := teamGroupMail(employee); mail != "" && len(cc) == 0 {
+		cc = append(cc, mail)
+	}
+	return cc
+}
+
+// absenceEvent builds the out-of-office event of an approved absence. The UID
+// is derived from the absence ID so a later CANCEL removes the same event.
+func absenceEvent(absence *database.Absence, employee *database.User, reason, method string) *smtp.Event {
+	from := viper.GetString("SMTP_FROM")
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	event := &smtp.Event{
+		UID:         fmt.Sprintf("absence-%d@%s", absence.ID, domain),
+		Method:      method,
+		Start:       absence.StartDate.In(dates.Location()),
+		End:         absence.EndDate.In(dates.Location()),
+		AllDay:      absence.Kind == AbsenceKindFullDay,
+		Summary:     fmt.Sprintf("%s: %s", fullName(employee), reason),
+		Description: absence.Description,
+		Organizer:   from,
+		Attendees:   []string{employee.Email},
+	}
+	if mail := teamGroupMail(employee); mail != "" {
+		event.Attendees = append(event.Attendees, mail)
+	}
+	if method == smtp.MethodCancel {
+		event.Sequence = 1
+	}
+	return event
+}
+
+// reasonName returns the name of the reason code in lang, the other
+// language's name when that one is empty, or the code itself
+func reasonName(code, lang string) string {
+	var reason database.AbsenceReason
+	if database.DB.Where("code = ?", code).First(&reason).Error != nil {
+		return code
+	}
+	names := []string{reason.NameMN, reason.NameEN}
+	if lang == "en" {
+		names[0], names[1] = names[1], names[0]
+	}
+	for _, name := range names {
+		if name != "" {
+			return name
+		}
+	}
+	return code
+}
+
+// absenceMailData builds the template variables, the reason named in lang
+func absenceMailData(absence *database.Absence, employee, leader *database.User, lang string) map[string]interface{} {
+	return map[string]interface{}{
+		"absence_id":      absence.ID,
+		"employee_name":   fullName(employee),
+		"employee_email":  employee.Email,
+		"leader_name":     fullName(leader),
+		"start_date":      absence.StartDate,
+		"end_date":        absence.EndDate,
+		"kind":            absence.Kind,
+		"reason":          reasonName(absence.Reason, lang),
+		"in_active_hours": absence.InActiveHours,
+		"description":     absence.Description,
+		"status":          absence.Status,
+		"comment":         absence.Comment,
+	}
+}
+
+// recipientLanguage returns the user's notification language, empty for the default
+func recipientLanguage(tx *gorm.DB, user *database.User) string {
+	var pref database.UserPreference
+	if user == nil || tx.Where("user_id = ?", user.ID).Limit(1).Find(&pref).Error != nil {
+		return ""
+	}
+	return pref.Language
+}
+
+// mailLanguage returns the language the user's email template is rendered in
+func mailLanguage(tx *gorm.DB, user *database.User) string {
+	if lang := recipientLanguage(tx, user); lang != "" {
+		return lang
+	}
+	return smtp.Templates.DefaultLanguage()
+}
+
+// queueNotification renders the mail and writes it to the outbox inside tx.
+// A template problem is logged and skipped so it never fails the request;
+// only a database error aborts the transaction.
+func queueNotification(tx *gorm.DB, absence *database.Absence, input smtp.EmailInput, data map[string]interface{}) error {
+	if input.Email == "" {
+		fmt.Println("Notification", input.Template, "skipped, no recipient")
+		return nil
+	}
+	msg, err := smtp.Compose(input, data)
+	if err != nil {
+		fmt.Println("Notification", input.Template, "skipped, render failed", err)
+		return nil
+	}
+	_, err = outbox.Enqueue(tx, input.Template, msg, &absence.ID)
+	return err
+}
+
+// notifyAbsenceRequested queues the request email to the leader with
+// one-click approve and reject links
+func notifyAbsenceRequested(tx *gorm.DB, absence *database.Absence, employee, leader *database.User) error {
+	data := absenceMailData(absence, employee, leader, mailLanguage(tx, leader))
+	if leader.Email != "" {
+		for key, action := range map[string]string{"approve_url": AbsenceStatusApproved, "reject_url": AbsenceStatusRejected} {
+			link, err := issueActionLink(tx, absence, leader.ID, action)
+			if err != nil {
+				return err
+			}
+			data[key] = link
+		}
+	}
+	if err := queueNotification(tx, absence, smtp.EmailInput{
+		Template: templateAbsenceRequest,
+		Email:    leader.Email,
+		Language: recipientLanguage(tx, leader),
+		Cc:       teamCc(employee),
+	}, data); err != nil {
+		return err
+	}
+	return queueTelegram(tx, absence, leader, templateAbsenceRequest, data)
+}
+
+// notifyAbsenceDecided queues the decision email with the leader's comment to the employee
+func notifyAbsenceDecided(tx *gorm.DB, absence *database.Absence, decider *database.User) error {
+	var employee database.User
+	if err := tx.Preload("Team").First(&employee, absence.EmployeeID).Error; err != nil {
+		fmt.Println("Notification skipped, employee not found", absence.EmployeeID, err)
+		return nil
+	}
+	data := absenceMailData(absence, &employee, decider, mailLanguage(tx, &employee))
+	input := smtp.EmailInput{
+		Template: templateAbsenceApproved,
+		Email:    employee.Email,
+		Language: recipientLanguage(tx, &employee),
+		Cc:       teamCc(&employee),
+	}
+	if absence.Status == AbsenceStatusRejected {
+		input.Template = templateAbsenceRejected
+	} else if calendarInvites() {
+		input.Calendar = absenceEvent(absence, &employee, fmt.Sprint(data["reason"]), smtp.MethodRequest)
+		input.Cc = calendarCc(&employee)
+	}
+	if err := queueNotification(tx, absence, input, data); err != nil {
+		return err
+	}
+	return queueTelegram(tx, absence, &employee, input.Template, data)
+}
+
+// notifyAbsenceCancelled tells the employee, and the leader when the request
+// was still pending and someone else cancelled it. An approved absence also
+// gets a CANCEL invite so the event disappears from the calendars it was
+// added to.
+func notifyAbsenceCancelled(tx *gorm.DB, absence *database.Absence, previousStatus string, canceller *database.User) error {
+	var employee, leader database.User
+	if err := tx.Preload("Team").First(&employee, absence.EmployeeID).Error; err != nil {
+		fmt.Println("Notification skipped, employee not found", absence.EmployeeID, err)
+		return nil
+	}
+	tx.Limit(1).Find(&leader, absence.LeaderID)
+
+	data := absenceMailData(absence, &employee, &leader, mailLanguage(tx, &employee))
+	data["cancelled_by"] = fullName(canceller)
+	data["comment"] = absence.CancelComment
+	input := smtp.EmailInput{
+		Template: templateAbsenceCancelled,
+		Email:    employee.Email,
+		Language: recipientLanguage(tx, &employee),
+		Cc:       teamCc(&employee),
+	}
+	if previousStatus == AbsenceStatusApproved && calendarInvites() {
+		input.Calendar = absenceEvent(absence, &employee, fmt.Sprint(data["reason"]), smtp.MethodCancel)
+		input.Cc = calendarCc(&employee)
+	}
+	// Хүлээгдэж буй хүсэлтийг шийдэх ёстой байсан ахлагч л мэдэх хэрэгтэй
+	if previousStatus == AbsenceStatusPending && leader.Email != "" && leader.ID != canceller.ID {
+		input.Cc = append(input.Cc, leader.Email)
+	}
+	return queueNotification(tx, absence, input, data)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"mcp-server/database"
+	"mcp-server/outbox"
+	"net/http"
+)
+
+func listOutbox(args map[string]interface{}) (interface{}, error) {
+	query := database.DB.Order("id DESC")
+	if status := stringArg(args, "status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if id, ok := floatArg(args, "absence_id"); ok && id > 0 {
+		query = query.Where("absence_id = ?", uint(id))
+	}
+	limit := 50
+	if n, ok := floatArg(args, "limit"); ok && n > 0 && n <= 500 {
+		limit = int(n)
+	}
+
+	var messages []database.OutboxMessage
+	if err := query.Limit(limit).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"max_attempts": outbox.MaxAttempts(),
+		"messages":     messages,
+	}, nil
+}
+
+func retryOutboxMessage(args map[string]interface{}) (interface{}, error) {
+	id, err := uintArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+	record, err := outbox.Retry(id)
+	switch {
+	case errors.Is(err, outbox.ErrNotFound):
+		return nil, NewToolError(http.StatusNotFound, "Outbox message not found")
+	case errors.Is(err, outbox.ErrAlreadySent), errors.Is(err, outbox.ErrSending):
+		return nil, NewToolError(http.StatusConflict, "%s", err)
+	case err != nil:
+		return nil, err
+	}
+	return record, nil
+}
@@ -0,0 +1,276 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mcp-server/database"
+	"mcp-server/smtp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Outbox мессежийн төлөвүүд
+const (
+	StatusPending = "pending"
+	StatusSending = "sending" // Worker авсан, lease_until хүртэл бусад нь авахгүй
+	StatusSent    = "sent"
+	StatusDead    = "dead" // MaxAttempts хүрээд орхисон
+)
+
+const (
+	defaultMaxAttempts  = 8
+	defaultPollInterval = 10 * time.Second
+	defaultBaseDelay    = 30 * time.Second
+	maxDelay            = 6 * time.Hour
+	batchSize           = 20
+	// Илгээж дуусаагүй worker унасан бол lease дууссаны дараа дахин авна,
+	// SMTP-ийн timeout-оос урт байх ёстой
+	leaseDuration = 5 * time.Minute
+)
+
+// Мэдэгдлийн сувгууд
+const (
+	ChannelEmail    = "email"
+	ChannelTelegram = "telegram"
+)
+
+var (
+	ErrNotFound    = errors.New("outbox message not found")
+	ErrAlreadySent = errors.New("outbox message was already sent")
+	ErrSending     = errors.New("outbox message is being sent")
+)
+
+// Sender нь имэйлээс бусад сувгийн payload-ийг хүргэнэ
+type Sender func(payload []byte) error
+
+var senders = map[string]Sender{}
+
+// Register sets how messages of channel are delivered, e.g. telegram.Deliver
+func Register(channel string, sender Sender) {
+	senders[channel] = sender
+}
+
+// MaxAttempts is OUTBOX_MAX_ATTEMPTS, after which a message is dead-lettered
+func MaxAttempts() int {
+	if n := viper.GetInt("OUTBOX_MAX_ATTEMPTS"); n > 0 {
+		return n
+	}
+	return defaultMaxAttempts
+}
+
+// Backoff returns the delay before the next attempt: OUTBOX_BASE_DELAY
+// doubled per failed attempt, capped at six hours
+func Backoff(attempts int) time.Duration {
+	base := viper.GetDuration("OUTBOX_BASE_DELAY")
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// Enqueue stores msg for delivery. Pass the transaction that changes the
+// absence so the mail is only sent when the change is committed.
+func Enqueue(tx *gorm.DB, template string, msg *smtp.Message, absenceID *uint) (*database.OutboxMessage, error) {
+	msg.EnsureMessageID()
+	return EnqueuePayload(tx, ChannelEmail, template, strings.Join(msg.Recipients(), ", "), msg.Subject, msg, absenceID)
+}
+
+// EnqueuePayload stores a message for another channel; payload is JSON encoded
+// and handed to the Sender registered for channel
+func EnqueuePayload(tx *gorm.DB, channel, template, recipient, subject string, payload interface{}, absenceID *uint) (*database.OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	record := &database.OutboxMessage{
+		Channel:       channel,
+		Template:      template,
+		Recipient:     recipient,
+		Subject:       subject,
+		Payload:       string(data),
+		Status:        StatusPending,
+		NextAttemptAt: time.Now(),
+		AbsenceID:     absenceID,
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Retry puts a message back in the queue for an immediate attempt
+func Retry(id uint) (*database.OutboxMessage, error) {
+	var record database.OutboxMessage
+	if err := database.DB.First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	switch record.Status {
+	case StatusSent:
+		return nil, ErrAlreadySent
+	case StatusSending:
+		return nil, ErrSending
+	}
+	record.Status = StatusPending
+	record.Attempts = 0
+	record.NextAttemptAt = time.Now()
+	if err := database.DB.Model(&record).Updates(map[string]interface{}{
+		"status":          record.Status,
+		"attempts":        record.Attempts,
+		"next_attempt_at": record.NextAttemptAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Run delivers due messages every OUTBOX_POLL_INTERVAL until ctx is done
+func Run(ctx context.Context, mailer smtp.Mailer) {
+	interval := viper.GetDuration("OUTBOX_POLL_INTERVAL")
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := ProcessDue(mailer); err != nil {
+			fmt.Println("Outbox worker error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue delivers the messages whose next attempt is due and returns
+// how many were sent. Each batch is claimed in a short transaction with
+// SKIP LOCKED so several server instances can run the worker, and delivered
+// after it commits so no row lock is held during network calls.
+func ProcessDue(mailer smtp.Mailer) (int, error) {
+	sent := 0
+	for {
+		n, done, err := processBatch(mailer)
+		sent += n
+		if err != nil || done {
+			return sent, err
+		}
+	}
+}
+
+func processBatch(mailer smtp.Mailer) (sent int, done bool, err error) {
+	batch, err := claim()
+	if err != nil {
+		return 0, true, err
+	}
+	for i := range batch {
+		if deliver(mailer, &batch[i]) {
+			sent++
+		}
+	}
+	return sent, len(batch) < batchSize, nil
+}
+
+// claim marks up to batchSize due messages as sending until leaseDuration
+// from now and keeps that lease_until on each row. Messages whose lease ran
+// out are claimed again with a new lease_until.
+func claim() ([]database.OutboxMessage, error) {
+	var batch []database.OutboxMessage
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND lease_until < ?)", StatusPending, now, StatusSending, now).
+			Order("next_attempt_at").Limit(batchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		// Postgres микросекунд хадгалдаг тул deliver-ийн тэнцүүлэлт таарахаар таслана
+		lease := now.Add(leaseDuration).Truncate(time.Microsecond)
+		ids := make([]uint, 0, len(batch))
+		for i := range batch {
+			ids = append(ids, batch[i].ID)
+			batch[i].Status = StatusSending
+			batch[i].LeaseUntil = &lease
+		}
+		return tx.Model(&database.OutboxMessage{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":      StatusSending,
+			"lease_until": lease,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// send hands the payload to the channel's transport
+func send(mailer smtp.Mailer, record *database.OutboxMessage) error {
+	if record.Channel != "" && record.Channel != ChannelEmail {
+		sender, ok := senders[record.Channel]
+		if !ok {
+			return fmt.Errorf("no sender for channel %q", record.Channel)
+		}
+		return sender([]byte(record.Payload))
+	}
+	var msg smtp.Message
+	if err := json.Unmarshal([]byte(record.Payload), &msg); err != nil {
+		return err
+	}
+	return mailer.Deliver(&msg)
+}
+
+// deliver attempts one claimed message and records the outcome, only while
+// the row still carries the lease this worker claimed it with. A sent
+// message's payload is cleared: it can hold one-click action links, which
+// must not outlive the delivery in the database.
+func deliver(mailer smtp.Mailer, record *database.OutboxMessage) bool {
+	err := send(mailer, record)
+
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": record.Attempts + 1, "lease_until": nil}
+	if err == nil {
+		updates["status"] = StatusSent
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+		updates["payload"] = ""
+	} else {
+		updates["last_error"] = err.Error()
+		if record.Attempts+1 >= MaxAttempts() {
+			updates["status"] = StatusDead
+			fmt.Println("Outbox message", record.ID, "dead-lettered after", record.Attempts+1, "attempts:", err)
+		} else {
+			updates["status"] = StatusPending
+			updates["next_attempt_at"] = now.Add(Backoff(record.Attempts + 1))
+			fmt.Println("Outbox message", record.ID, "failed, retrying later:", err)
+		}
+	}
+	// Lease дуусч өөр worker дахин авсан бол lease_until өөрчлөгдсөн байх тул
+	// түүний үр дүн, оролдлогын тоог дарахгүй
+	result := database.DB.Model(&database.OutboxMessage{}).
+		Where("id = ? AND status = ? AND lease_until = ?", record.ID, StatusSending, record.LeaseUntil).
+		Updates(updates)
+	if result.Error != nil {
+		fmt.Println("Failed to update outbox message", record.ID, result.Error)
+	} else if result.RowsAffected == 0 {
+		fmt.Println("Outbox message", record.ID, "lease expired and was claimed again, result discarded")
+	}
+	return err == nil
+}
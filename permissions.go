@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mcp-server/audit"
+	"mcp-server/auth"
+	"mcp-server/database"
+	"mcp-server/rbac"
+	"net/http"
+	"slices"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Ажилтан: хүсэлт гаргах, мэдээлэл харах
+var requestTools = []string{
+	"set_notification_language",
+	"get_teams",
+	"get_users",
+	"get_user_profile",
+	"get_effective_permissions",
+	"create_absence_request",
+	"cancel_absence",
+	"list_absences",
+	"get_time_intervals",
+	"upload_absence_attachment",
+	"list_absence_attachments",
+	"download_absence_attachment",
+	"list_absence_reasons",
+}
+
+// Удирдагч: хүсэлт шийдэх
+var approvalTools = []string{
+	"approve_absence",
+	"reject_absence",
+}
+
+// Хүний нөөц, админ: тайлан, тохиргоо
+var managementTools = []string{
+	"get_absence_report",
+	"create_absence_reason",
+	"update_absence_reason",
+	"set_user_role",
+	"reveal_registration_number",
+	"list_outbox",
+	"retry_outbox_message",
+	"preview_email",
+}
+
+func toolSet(groups ...[]string) []string {
+	var tools []string
+	for _, group := range groups {
+		tools = append(tools, group...)
+	}
+	return tools
+}
+
+// RBAC_POLICY_FILE өгөөгүй үед хэрэглэх бодлого
+var defaultPolicy = rbac.Policy{
+	UserRoleEmployee:        {Tools: toolSet(requestTools), DataScope: rbac.ScopeOwn},
+	UserRoleTeamLeader:      {Tools: toolSet(requestTools, approvalTools), DataScope: rbac.ScopeTeam},
+	UserRoleTeamleaderandHR: {Tools: toolSet(requestTools, approvalTools, managementTools), DataScope: rbac.ScopeAll},
+	UserRoleAdmin:           {Tools: toolSet(requestTools, approvalTools, managementTools), DataScope: rbac.ScopeAll},
+	UserRoleCeo:             {Tools: toolSet(requestTools, approvalTools, managementTools), DataScope: rbac.ScopeAll},
+}
+
+var policy = defaultPolicy
+
+// ConfigurePolicy loads RBAC_POLICY_FILE on top of the default policy
+func ConfigurePolicy() error {
+	loaded, err := rbac.LoadPolicy(viper.GetString("RBAC_POLICY_FILE"), defaultPolicy)
+	if err != nil {
+		return err
+	}
+	policy = loaded
+	return nil
+}
+
+// Caller хэрэгсэл дуудаж буй хэрэглэгч, токен болон эрхийн хамт
+type Caller struct {
+	*database.User
+	Identity    *auth.Identity
+	Permissions rbac.Permissions
+}
+
+func NewCaller(identity *auth.Identity) (*Caller, error) {
+	perms, err := policy.For(database.DB, identity.User)
+	if err != nil {
+		return nil, err
+	}
+	return &Caller{User: identity.User, Identity: identity, Permissions: perms}, nil
+}
+
+// callerFromRequest builds the caller for HTTP handlers behind auth.Middleware
+func callerFromRequest(r *http.Request) (*Caller, error) {
+	identity, ok := auth.FromContext(r.Context())
+	if !ok {
+		return nil, NewToolError(http.StatusUnauthorized, "Unauthorized")
+	}
+	return NewCaller(identity)
+}
+
+// CanUse reports whether both the role and the token allow the tool
+func (c *Caller) CanUse(tool string) bool {
+	if !c.Permissions.CanUse(tool) {
+		return false
+	}
+	scope := toolScopes[tool]
+	return scope == "" || c.Identity.HasScope(scope)
+}
+
+// CanAccessUser reports whether the user's data is inside the caller's data scope
+func (c *Caller) CanAccessUser(userID uint) bool {
+	if userID == c.ID || c.Permissions.DataScope == rbac.ScopeAll {
+		return true
+	}
+	var owner database.User
+	if err := database.DB.Select("id, team_id").First(&owner, userID).Error; err != nil {
+		return false
+	}
+	return c.Permissions.CanAccess(c.ID, owner.ID, owner.TeamID)
+}
+
+// CanAccessAbsence allows the employee, creator and assigned leader, plus
+// anyone whose data scope covers the employee.
+func (c *Caller) CanAccessAbsence(absence *database.Absence) bool {
+	if c.ID == absence.EmployeeID || c.ID == absence.CreatedUserID || c.ID == absence.LeaderID {
+		return true
+	}
+	return c.CanAccessUser(absence.EmployeeID)
+}
+
+// CanDecideAbsence allows the assigned leader, or callers seeing everything
+// except on their own absence
+func (c *Caller) CanDecideAbsence(absence *database.Absence) bool {
+	if absence.LeaderID == c.ID {
+		return true
+	}
+	return absence.EmployeeID != c.ID && c.Permissions.DataScope == rbac.ScopeAll
+}
+
+// ScopeAbsences restricts an absence query to the caller's data scope
+func (c *Caller) ScopeAbsences(query *gorm.DB) *gorm.DB {
+	switch c.Permissions.DataScope {
+	case rbac.ScopeAll:
+		return query
+	case rbac.ScopeTeam:
+		teamIDs := append([]uint{0}, c.Permissions.TeamIDs...)
+		return query.Where("employee_id = ? OR leader_id = ? OR employee_id IN (?)", c.ID, c.ID,
+			database.DB.Model(&database.User{}).Select("id").Where("team_id IN ?", teamIDs))
+	default:
+		return query.Where("employee_id = ? OR leader_id = ?", c.ID, c.ID)
+	}
+}
+
+func getEffectivePermissions(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	target := caller.User
+	if id, ok := floatArg(args, "user_id"); ok && uint(id) != caller.ID {
+		target = &database.User{}
+		if err := database.DB.First(target, uint(id)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, NewToolError(http.StatusNotFound, "User not found")
+			}
+			return nil, err
+		}
+	} else if email := stringArg(args, "email"); email != "" && email != caller.Email {
+		target = &database.User{}
+		if err := database.DB.Where("email = ?", email).First(target).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, NewToolError(http.StatusNotFound, "User not found")
+			}
+			return nil, err
+		}
+	}
+	if target.ID != caller.ID && caller.Permissions.DataScope != rbac.ScopeAll {
+		return nil, NewToolError(http.StatusForbidden, "Only administrators can inspect other users' permissions")
+	}
+
+	perms, err := policy.For(database.DB, target)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"user_id":     target.ID,
+		"email":       target.Email,
+		"permissions": perms,
+	}
+	if target.ID == caller.ID {
+		result["token_scopes"] = caller.Identity.Scopes
+		result["auth_method"] = caller.Identity.Method
+	}
+	return result, nil
+}
+
+// setUserRole changes a user's role and refreshes the tool lists of their open
+// sessions. Both the target's current role chain and the new role must be
+// among the caller's own roles, so nobody can promote past or demote above
+// themselves.
+func setUserRole(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	userID, err := uintArg(args, "user_id")
+	if err != nil {
+		return nil, err
+	}
+	roleName, err := requiredStringArg(args, "role")
+	if err != nil {
+		return nil, err
+	}
+	if userID == caller.ID {
+		return nil, NewToolError(http.StatusForbidden, "You cannot change your own role")
+	}
+	// Өөрт байхгүй эрхийг бусдад олгохгүй, эцэг эрхүүд нь өөрийн эрхэд багтана
+	if !slices.Contains(caller.Permissions.Roles, roleName) {
+		return nil, NewToolError(http.StatusForbidden, "You can only assign roles you hold")
+	}
+
+	var role database.Role
+	if err := database.DB.Where("name = ?", roleName).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "Role %q not found", roleName)
+		}
+		return nil, err
+	}
+	var user database.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "User not found")
+		}
+		return nil, err
+	}
+	// Өөрөөсөө дээд эрхтэй хэрэглэгчийг (жишээ нь CEO, admin) бууруулахгүй
+	current, err := rbac.RoleChain(database.DB, user.RoleID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	for _, held := range current {
+		if !slices.Contains(caller.Permissions.Roles, held.Name) {
+			return nil, NewToolError(http.StatusForbidden, "You cannot change the role of a user with the %q role", current[0].Name)
+		}
+	}
+	// Аудитын бичлэггүйгээр эрх өөрчлөхгүй
+	detail := map[string]interface{}{"role": role.Name, "previous_role_id": user.RoleID}
+	if err := audit.Record(caller.ID, audit.ActionSetRole, "user", []uint{user.ID}, detail); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(&user).Update("role_id", role.ID).Error; err != nil {
+		return nil, err
+	}
+	fmt.Println("Role of user", user.ID, "set to", role.Name, "by", caller.ID)
+
+	refreshUserSessions(user.ID)
+	user.RoleID = role.ID
+	perms, err := policy.For(database.DB, &user)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"user_id": user.ID, "role": role.Name, "permissions": perms}, nil
+}
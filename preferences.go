@@ -0,0 +1,39 @@
+package main
+
+import (
+	"mcp-server/database"
+	"mcp-server/smtp"
+	"net/http"
+
+	"gorm.io/gorm/clause"
+)
+
+// Мэдэгдлийн дэмжигдсэн хэлүүд
+var notificationLanguages = []string{"mn", "en"}
+
+func setNotificationLanguage(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	language, err := requiredStringArg(args, "language")
+	if err != nil {
+		return nil, err
+	}
+	supported := false
+	for _, l := range notificationLanguages {
+		supported = supported || l == language
+	}
+	if !supported {
+		return nil, NewToolError(http.StatusBadRequest, "Unsupported language %q, use mn or en", language)
+	}
+
+	pref := database.UserPreference{UserID: caller.ID, Language: language}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"language", "updated_at"}),
+	}).Create(&pref).Error; err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"user_id":          caller.ID,
+		"language":         language,
+		"default_language": smtp.Templates.DefaultLanguage(),
+	}, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"mcp-server/auth"
+	"mcp-server/smtp"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// EmailPreview preview_email болон /admin/email-preview-ийн хариу
+type EmailPreview struct {
+	*smtp.Rendered
+	Variables  smtp.Variables         `json:"variables"`
+	Data       map[string]interface{} `json:"data"`
+	Languages  []string               `json:"languages"`
+	SampleUsed bool                   `json:"sample_used"`
+}
+
+// buildEmailPreview renders name with its sample data overlaid by data
+func buildEmailPreview(name, lang, subtitle string, data map[string]interface{}) (*EmailPreview, error) {
+	languages, ok := smtp.Templates.Names()[name]
+	if !ok {
+		return nil, NewToolError(http.StatusNotFound, "Email template %q not found", name)
+	}
+	merged, err := smtp.Templates.Sample(name)
+	if err != nil {
+		return nil, err
+	}
+	sampleUsed := len(merged) > 0
+	for k, v := range data {
+		merged[k] = v
+	}
+	if subtitle != "" {
+		merged["subtitle"] = subtitle
+	}
+
+	rendered, err := smtp.Templates.Preview(name, lang, merged)
+	if err != nil {
+		if errors.Is(err, smtp.ErrTemplateNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "%s", err)
+		}
+		return nil, NewToolError(http.StatusUnprocessableEntity, "%s", err)
+	}
+	vars, err := smtp.Templates.Variables(name, rendered.Language)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailPreview{Rendered: rendered, Variables: vars, Data: merged, Languages: languages, SampleUsed: sampleUsed}, nil
+}
+
+func emailTemplateNames() []string {
+	var names []string
+	for name := range smtp.Templates.Names() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func previewEmail(args map[string]interface{}) (interface{}, error) {
+	name, err := requiredStringArg(args, "template")
+	if err != nil {
+		return nil, err
+	}
+	data, _ := args["data"].(map[string]interface{})
+	return buildEmailPreview(name, stringArg(args, "language"), stringArg(args, "subtitle"), data)
+}
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>Email preview: {{.Template}}</title>
+  <style>
+    body { font-family: sans-serif; margin: 24px; }
+    .missing { color: #b00020; }
+    iframe { width: 100%; height: 480px; border: 1px solid #ccc; }
+    pre { background: #f6f6f6; padding: 12px; white-space: pre-wrap; }
+  </style>
+</head>
+<body>
+  <p><a href="/admin/email-preview">All templates</a> |
+    {{range .Languages}}<a href="?lang={{.}}">{{.}}</a> {{end}}</p>
+  <h2>{{.Template}} ({{.Language}})</h2>
+  {{if .Missing}}<p class="missing"><strong>Missing variables:</strong> {{range .Missing}}{{.}} {{end}}</p>{{end}}
+  <p><strong>Required:</strong> {{range .Variables.Required}}{{.}} {{end}}<br>
+    <strong>Optional:</strong> {{range .Variables.Optional}}{{.}} {{end}}</p>
+  <h3>Subject</h3>
+  <pre>{{.Subject}}</pre>
+  <h3>HTML</h3>
+  <iframe sandbox srcdoc="{{.HTML}}"></iframe>
+  <h3>Text</h3>
+  <pre>{{.Text}}</pre>
+  <h3>Data</h3>
+  <pre>{{.DataJSON}}</pre>
+</body>
+</html>
+`))
+
+var previewIndexPage = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>Email templates</title></head>
+<body>
+  <h2>Email templates</h2>
+  <ul>{{range .}}<li><a href="/admin/email-preview/{{.}}">{{.}}</a></li>{{end}}</ul>
+</body>
+</html>
+`))
+
+// EmailPreviewHandler GET /admin/email-preview/{template}?lang=en&subtitle=..
+// renders with sample data; POST the same path with a JSON object to supply
+// data. ?format=json returns the preview as JSON.
+func EmailPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if !auth.RequireScope(w, r, auth.ScopeAdmin) {
+		return
+	}
+	caller, err := callerFromRequest(r)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+	if !caller.CanUse("preview_email") {
+		http.Error(w, "Your role is not allowed to preview emails", http.StatusForbidden)
+		return
+	}
+
+	name := r.PathValue("template")
+	if name == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		previewIndexPage.Execute(w, emailTemplateNames())
+		return
+	}
+
+	var data map[string]interface{}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&data); err != nil {
+			http.Error(w, "Body must be a JSON object: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	query := r.URL.Query()
+	preview, err := buildEmailPreview(name, query.Get("lang"), query.Get("subtitle"), data)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+
+	if query.Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(preview)
+		return
+	}
+	dataJSON, _ := json.MarshalIndent(preview.Data, "", "  ")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := previewPage.Execute(w, struct {
+		*EmailPreview
+		DataJSON string
+	}{preview, string(dataJSON)}); err != nil {
+		fmt.Println("Failed to render preview page", err)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mcp-server/database"
+	"mcp-server/images"
+	"mcp-server/storage"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// UserProfile чат агент, имэйлийн загварт харуулах профайл. Хувийн мэдээлэл
+// нь UserView-ийн түвшингээр хязгаарлагдана.
+type UserProfile struct {
+	UserView
+	TeamName      string            `json:"team_name"`
+	ProfileImages map[string]string `json:"profile_images,omitempty"` // хэмжээ -> URL
+	CoverImageURL string            `json:"cover_image_url,omitempty"`
+}
+
+func thumbnailCache() images.Cache {
+	dir := viper.GetString("THUMBNAIL_CACHE_DIR")
+	if dir == "" {
+		dir = filepath.Join(storage.Root(), "thumbnails")
+	}
+	return images.Cache{Dir: dir}
+}
+
+const defaultAvatarURLExpiry = 90 * 24 * time.Hour
+
+// avatarURLExpiry is how long avatar links stay valid, AVATAR_URL_EXPIRY.
+// Email templates embed them, so they must outlive the mail being read.
+func avatarURLExpiry() time.Duration {
+	if d := viper.GetDuration("AVATAR_URL_EXPIRY"); d > 0 {
+		return d
+	}
+	return defaultAvatarURLExpiry
+}
+
+// avatarKey is what an avatar link signs. It names the profile file so a
+// new picture invalidates links to the old one.
+func avatarKey(userID uint64, profileID uint, size string) string {
+	return fmt.Sprintf("avatars/%d/%d/%s", userID, profileID, size)
+}
+
+// AvatarURL returns the signed thumbnail URL of the user's profile picture,
+// valid for avatarURLExpiry; empty when the user has none
+func AvatarURL(user *database.User, size int) string {
+	if user.ProfileID == nil {
+		return ""
+	}
+	query := storage.SignQuery(avatarKey(uint64(user.ID), *user.ProfileID, strconv.Itoa(size)), avatarURLExpiry())
+	query.Set("size", strconv.Itoa(size))
+	return PublicURL(fmt.Sprintf("/users/%d/avatar?%s", user.ID, query.Encode()))
+}
+
+// NewUserProfile builds the profile of user as the caller may see it
+func NewUserProfile(caller *Caller, user *database.User) UserProfile {
+	profile := UserProfile{UserView: NewUserView(caller, user, false)}
+	if user.Team != nil {
+		profile.TeamName = user.Team.Name
+	}
+	if user.ProfileID != nil {
+		profile.ProfileImages = map[string]string{}
+		for _, size := range images.Sizes {
+			profile.ProfileImages[strconv.Itoa(size)] = AvatarURL(user, size)
+		}
+	}
+	if user.Cover != nil {
+		if url, err := storage.SignedURL(context.Background(), user.Cover.PhysicalPath, signedURLExpiry); err == nil {
+			profile.CoverImageURL = url
+		}
+	}
+	return profile
+}
+
+func getUserProfile(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	query := database.DB.Preload("Team").Preload("Cover")
+	if id, ok := floatArg(args, "user_id"); ok && id > 0 {
+		query = query.Where("id = ?", uint(id))
+	} else if email := stringArg(args, "email"); email != "" {
+		query = query.Where("email = ?", email)
+	} else {
+		return nil, NewToolError(http.StatusBadRequest, "user_id or email is required")
+	}
+
+	var user database.User
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "User not found")
+		}
+		return nil, err
+	}
+	return NewUserProfile(caller, &user), nil
+}
+
+// AvatarHandler GET /users/{id}/avatar?size=128&expires=..&signature=..,
+// профайл зургийн thumbnail. Зөвхөн AvatarURL-ээр үүсгэсэн холбоос ажиллана.
+func AvatarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+	var user database.User
+	if err := database.DB.Preload("Profile").First(&user, userID).Error; err != nil || user.Profile == nil {
+		http.Error(w, "Profile image not found", http.StatusNotFound)
+		return
+	}
+	value := r.URL.Query().Get("size")
+	if err := storage.VerifySignedURL(avatarKey(userID, user.Profile.ID, value), r.URL.Query()); err != nil {
+		http.Error(w, "Invalid or expired link", http.StatusForbidden)
+		return
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size <= 0 {
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+	size = images.NearestSize(size)
+
+	key := user.Profile.PhysicalPath
+	data, contentType, err := thumbnailCache().Get(key, size, func() ([]byte, error) {
+		return storage.ReadAll(r.Context(), key)
+	})
+	if err != nil {
+		fmt.Println("Failed to build avatar", userID, err)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "Profile image not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, images.ErrUnsupported) {
+			http.Error(w, "Profile image format is not supported", http.StatusUnprocessableEntity)
+			return
+		}
+		if errors.Is(err, images.ErrTooLarge) {
+			http.Error(w, "Profile image is too large", http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Failed to load profile image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	// Холбоос нь зургийн файлаар ялгаатай тул нэг өдөр кэшлэхэд аюулгүй
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(data)
+}
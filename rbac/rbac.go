@@ -0,0 +1,160 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"mcp-server/database"
+	"os"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Өгөгдөлд хандах хүрээ, багаас өргөн рүү
+const (
+	ScopeOwn  = "own"  // Зөвхөн өөрийн
+	ScopeTeam = "team" // Өөрийн болон удирддаг багийн
+	ScopeAll  = "all"  // Бүгд
+)
+
+var scopeRank = map[string]int{ScopeOwn: 0, ScopeTeam: 1, ScopeAll: 2}
+
+// RolePolicy нэг эрхийн хэрэгслүүд, өгөгдлийн хүрээ. "*" бүх хэрэгсэл.
+type RolePolicy struct {
+	Tools     []string `json:"tools"`
+	DataScope string   `json:"data_scope"`
+}
+
+// Policy эрхийн нэр -> бодлого
+type Policy map[string]RolePolicy
+
+// Permissions хэрэглэгчийн эрхийн гинжээс тооцсон эцсийн зөвшөөрөл
+type Permissions struct {
+	Roles     []string `json:"roles"`      // Өөрийн эрхээс эхлээд эцэг эрхүүд
+	Tools     []string `json:"tools"`      // Зөвшөөрөгдсөн хэрэгслүүд, "*" бүгд
+	DataScope string   `json:"data_scope"` // own, team, all
+	TeamIDs   []uint   `json:"team_ids"`   // team хүрээнд хамаарах багууд
+
+	tools map[string]bool
+}
+
+// LoadPolicy reads a JSON policy file; roles it defines replace the defaults
+func LoadPolicy(path string, defaults Policy) (Policy, error) {
+	policy := Policy{}
+	for name, rp := range defaults {
+		policy[name] = rp
+	}
+	if path == "" {
+		return policy, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read RBAC policy: %w", err)
+	}
+	var overrides Policy
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parse RBAC policy: %w", err)
+	}
+	for name, rp := range overrides {
+		if _, ok := scopeRank[rp.DataScope]; !ok {
+			return nil, fmt.Errorf("role %q has unknown data_scope %q", name, rp.DataScope)
+		}
+		policy[name] = rp
+	}
+	return policy, nil
+}
+
+// RoleChain returns the role and its parents following ParentID
+func RoleChain(db *gorm.DB, roleID uint) ([]database.Role, error) {
+	var chain []database.Role
+	seen := map[uint]bool{}
+	for id := roleID; id != 0 && !seen[id]; {
+		seen[id] = true
+		var role database.Role
+		if err := db.First(&role, id).Error; err != nil {
+			if len(chain) > 0 {
+				break
+			}
+			return nil, err
+		}
+		chain = append(chain, role)
+		id = role.ParentID
+	}
+	return chain, nil
+}
+
+// Resolve combines the policies of every role in the chain: tools are
+// united and the widest data scope wins.
+func (p Policy) Resolve(chain []database.Role) Permissions {
+	perms := Permissions{DataScope: ScopeOwn, tools: map[string]bool{}}
+	for _, role := range chain {
+		perms.Roles = append(perms.Roles, role.Name)
+		rp, ok := p[role.Name]
+		if !ok {
+			continue
+		}
+		for _, tool := range rp.Tools {
+			perms.tools[tool] = true
+		}
+		if scopeRank[rp.DataScope] > scopeRank[perms.DataScope] {
+			perms.DataScope = rp.DataScope
+		}
+	}
+	for tool := range perms.tools {
+		perms.Tools = append(perms.Tools, tool)
+	}
+	sort.Strings(perms.Tools)
+	return perms
+}
+
+// For resolves the permissions of user, including the teams they lead
+func (p Policy) For(db *gorm.DB, user *database.User) (Permissions, error) {
+	var chain []database.Role
+	if user.RoleID != 0 {
+		var err error
+		if chain, err = RoleChain(db, user.RoleID); err != nil {
+			return Permissions{}, err
+		}
+	}
+	perms := p.Resolve(chain)
+
+	if user.TeamID != 0 {
+		perms.TeamIDs = append(perms.TeamIDs, user.TeamID)
+	}
+	var led []uint
+	if err := db.Model(&database.Team{}).Where("leader_id = ?", user.ID).Pluck("id", &led).Error; err != nil {
+		return Permissions{}, err
+	}
+	for _, id := range led {
+		if id != user.TeamID {
+			perms.TeamIDs = append(perms.TeamIDs, id)
+		}
+	}
+	return perms, nil
+}
+
+// CanUse reports whether the tool is allowed
+func (p Permissions) CanUse(tool string) bool {
+	return p.tools["*"] || p.tools[tool]
+}
+
+// CanAccess reports whether data owned by a user of teamID falls inside the
+// data scope of caller.
+func (p Permissions) CanAccess(callerID, ownerID, ownerTeamID uint) bool {
+	switch p.DataScope {
+	case ScopeAll:
+		return true
+	case ScopeTeam:
+		if ownerID == callerID {
+			return true
+		}
+		for _, id := range p.TeamIDs {
+			if id != 0 && id == ownerTeamID {
+				return true
+			}
+		}
+		return false
+	default:
+		return ownerID == callerID
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mcp-server/database"
+	"mcp-server/dates"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FindAbsenceReason matches the input against active reason codes and their
+// English/Mongolian names, case-insensitively.
+func FindAbsenceReason(input string) (*database.AbsenceReason, error) {
+	value := strings.ToLower(strings.TrimSpace(input))
+	var reason database.AbsenceReason
+	err := database.DB.Where("is_active = ? AND (LOWER(code) = ? OR LOWER(name_en) = ? OR LOWER(name_mn) = ?)", true, value, value, value).
+		First(&reason).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var codes []string
+		database.DB.Model(&database.AbsenceReason{}).Where("is_active = ?", true).Order("code").Pluck("code", &codes)
+		return nil, NewToolError(http.StatusBadRequest, "unknown reason %q, allowed: %s", input, strings.Join(codes, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reason, nil
+}
+
+// CheckReasonPolicy enforces the reason's maximum length and notice period
+func CheckReasonPolicy(r *database.AbsenceReason, period AbsencePeriod) error {
+	if r.MaxDays > 0 {
+		days := period.Hours / dates.WorkingCalendar().DayHours()
+		if days > r.MaxDays {
+			return NewToolError(http.StatusBadRequest, "%s allows at most %g days, requested %g", r.Code, r.MaxDays, days)
+		}
+	}
+	if r.NoticeDays > 0 {
+		earliest := dates.StartOfDay(dates.Now()).AddDate(0, 0, r.NoticeDays)
+		if period.Start.Before(earliest) {
+			return NewToolError(http.StatusBadRequest, "%s requires %d days notice, earliest start is %s", r.Code, r.NoticeDays, dates.FormatDate(earliest))
+		}
+	}
+	return nil
+}
+
+func listAbsenceReasons(args map[string]interface{}) (interface{}, error) {
+	query := database.DB.Order("code")
+	if includeInactive, _ := boolArg(args, "include_inactive"); !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	var reasons []database.AbsenceReason
+	if err := query.Find(&reasons).Error; err != nil {
+		return nil, err
+	}
+	return reasons, nil
+}
+
+func createAbsenceReason(args map[string]interface{}) (interface{}, error) {
+	code, err := requiredStringArg(args, "code")
+	if err != nil {
+		return nil, err
+	}
+	reason := database.AbsenceReason{Code: strings.ToLower(strings.TrimSpace(code))}
+	if reason.NameEN, err = requiredStringArg(args, "name_en"); err != nil {
+		return nil, err
+	}
+	if reason.NameMN, err = requiredStringArg(args, "name_mn"); err != nil {
+		return nil, err
+	}
+
+	var existing int64
+	database.DB.Model(&database.AbsenceReason{}).Where("code = ?", reason.Code).Count(&existing)
+	if existing > 0 {
+		return nil, NewToolError(http.StatusConflict, "reason %q already exists", reason.Code)
+	}
+
+	if err := database.DB.Create(&reason).Error; err != nil {
+		return nil, err
+	}
+	// bool талбаруудын default-оос болж Create үед false утга хадгалагдахгүй
+	if updates := reasonUpdates(args); len(updates) > 0 {
+		if err := database.DB.Model(&reason).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	database.DB.First(&reason, reason.ID)
+	fmt.Println("Absence reason created", reason.Code)
+	notifyAllSessions()
+	return reason, nil
+}
+
+func updateAbsenceReason(args map[string]interface{}) (interface{}, error) {
+	code, err := requiredStringArg(args, "code")
+	if err != nil {
+		return nil, err
+	}
+	var reason database.AbsenceReason
+	if err := database.DB.Where("code = ?", code).First(&reason).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "reason %q not found", code)
+		}
+		return nil, err
+	}
+
+	updates := reasonUpdates(args)
+	if value := stringArg(args, "name_en"); value != "" {
+		updates["name_en"] = value
+	}
+	if value := stringArg(args, "name_mn"); value != "" {
+		updates["name_mn"] = value
+	}
+	if len(updates) == 0 {
+		return nil, NewToolError(http.StatusBadRequest, "nothing to update")
+	}
+	if err := database.DB.Model(&reason).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	database.DB.First(&reason, reason.ID)
+	fmt.Println("Absence reason updated", reason.Code)
+	notifyAllSessions()
+	return reason, nil
+}
+
+// reasonUpdates collects the policy columns present in args
+func reasonUpdates(args map[string]interface{}) map[string]interface{} {
+	updates := map[string]interface{}{}
+	for _, name := range []string{"is_paid", "counts_against_balance", "requires_attachment", "is_active"} {
+		if value, ok := boolArg(args, name); ok {
+			updates[name] = value
+		}
+	}
+	if value, ok := floatArg(args, "max_days"); ok {
+		if value < 0 {
+			value = 0
+		}
+		updates["max_days"] = value
+	}
+	if value, ok := floatArg(args, "notice_days"); ok && value >= 0 {
+		updates["notice_days"] = int(value)
+	}
+	return updates
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mcp-server/audit"
+	"mcp-server/database"
+	"mcp-server/encryption"
+	"mcp-server/rbac"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// registrationNumber returns the user's РД, decrypting EncryptedRD when the
+// plaintext column has already been blanked by backfill-rd
+func registrationNumber(user *database.User) (string, error) {
+	if user.EncryptedRD != "" {
+		rd, err := encryption.Decrypt(user.EncryptedRD)
+		if err == nil || user.RegistrationNumber == "" {
+			return rd, err
+		}
+	}
+	return user.RegistrationNumber, nil
+}
+
+// maskedRegistrationNumber is the only form of РД the directory returns
+func maskedRegistrationNumber(user *database.User) string {
+	rd, err := registrationNumber(user)
+	if err != nil {
+		fmt.Println("Failed to decrypt registration number of user", user.ID, err)
+		return ""
+	}
+	return MaskRegistrationNumber(rd)
+}
+
+func revealRegistrationNumber(caller *Caller, args map[string]interface{}) (interface{}, error) {
+	if caller.Permissions.DataScope != rbac.ScopeAll {
+		return nil, NewToolError(http.StatusForbidden, "Only HR can reveal registration numbers")
+	}
+	userID, err := uintArg(args, "user_id")
+	if err != nil {
+		return nil, err
+	}
+	reason, err := requiredStringArg(args, "reason")
+	if err != nil {
+		return nil, err
+	}
+
+	var user database.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, NewToolError(http.StatusNotFound, "User not found")
+		}
+		return nil, err
+	}
+	rd, err := registrationNumber(&user)
+	if err != nil {
+		fmt.Println("Failed to decrypt registration number of user", user.ID, err)
+		return nil, NewToolError(http.StatusInternalServerError, "Registration number could not be decrypted")
+	}
+	if rd == "" {
+		return nil, NewToolError(http.StatusNotFound, "User has no registration number")
+	}
+
+	// Аудитын бичлэггүйгээр РД буцаахгүй
+	if err := audit.Record(caller.ID, audit.ActionRevealRD, "user", []uint{user.ID}, map[string]string{"reason": reason}); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"user_id": user.ID, "registration_number": rd}, nil
+}
+
+// BackfillRegistrationNumbers encrypts plain registration numbers into
+// encrypted_rd and blanks the plaintext column
+func BackfillRegistrationNumbers() error {
+	var users []database.User
+	if err := database.DB.Select("id, registration_number, encrypted_rd").
+		Where("registration_number IS NOT NULL AND registration_number <> ''").Find(&users).Error; err != nil {
+		return err
+	}
+
+	done := 0
+	for _, user := range users {
+		rd := strings.TrimSpace(user.RegistrationNumber)
+		encrypted, err := encryption.Encrypt(rd)
+		if err != nil {
+			return err
+		}
+		// Хоосолохоос өмнө буцааж тайлж шалгана
+		if check, err := encryption.Decrypt(encrypted); err != nil || check != rd {
+			return fmt.Errorf("user %d: encrypted value does not round-trip", user.ID)
+		}
+		if err := database.DB.Model(&database.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"encrypted_rd":        encrypted,
+			"registration_number": "",
+		}).Error; err != nil {
+			return fmt.Errorf("user %d: %w", user.ID, err)
+		}
+		done++
+	}
+	fmt.Printf("Encrypted %d registration number(s)\n", done)
+	return nil
+}
+
+// RotateRegistrationNumbers re-encrypts values sealed with an older key version
+func RotateRegistrationNumbers() error {
+	var users []database.User
+	if err := database.DB.Select("id, encrypted_rd").
+		Where("encrypted_rd IS NOT NULL AND encrypted_rd <> ''").Find(&users).Error; err != nil {
+		return err
+	}
+
+	keyring := encryption.Default()
+	rotated, skipped := 0, 0
+	for _, user := range users {
+		if !keyring.NeedsRotation(user.EncryptedRD) {
+			if _, err := encryption.Version(user.EncryptedRD); err != nil {
+				fmt.Println("Skipping user", user.ID, err)
+				skipped++
+			}
+			continue
+		}
+		encrypted, err := keyring.Rotate(user.EncryptedRD)
+		if err != nil {
+			return fmt.Errorf("user %d: %w", user.ID, err)
+		}
+		if err := database.DB.Model(&database.User{}).Where("id = ?", user.ID).Update("encrypted_rd", encrypted).Error; err != nil {
+			return fmt.Errorf("user %d: %w", user.ID, err)
+		}
+		rotated++
+	}
+	fmt.Printf("Rotated %d registration number(s) to v%d, skipped %d\n", rotated, keyring.Current, skipped)
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"mcp-server/secrets"
+
+	"github.com/spf13/viper"
+)
+
+// requiredSecrets lists the secrets checked at startup. Optional ones are
+// only checked when the feature using them is configured.
+func requiredSecrets() []secrets.Requirement {
+	reqs := []secrets.Requirement{
+		{Name: "DB_PASSWORD", Required: true, MinLength: 8},
+		{Name: "CIPHER_KEY", Required: true, MinLength: 16},
+		{Name: "SMTP_PASS", Required: smtpEnabled()},
+		{Name: "JWT_HS256_SECRET", MinLength: 32},
+		{Name: "OAUTH_CLIENT_SECRET", Required: viper.GetString("OAUTH_CLIENT_ID") != ""},
+		{Name: "STORAGE_SIGNING_KEY", MinLength: 32},
+		{Name: "ACTION_LINK_KEY", MinLength: 32},
+		{Name: "DKIM_PRIVATE_KEY", Required: viper.GetString("DKIM_DOMAIN") != ""},
+		{Name: "TELEGRAM_WEBHOOK_SECRET", Required: secrets.Get("TELEGRAM_BOT_TOKEN") != "", MinLength: 16},
+	}
+	if viper.GetString("STORAGE_BACKEND") == "s3" {
+		reqs = append(reqs, secrets.Requirement{Name: "S3_SECRET_KEY", Required: true})
+	}
+	return reqs
+}
+
+func smtpEnabled() bool {
+	driver := viper.GetString("MAIL_DRIVER")
+	return (driver == "" || driver == "smtp") && viper.GetString("SMTP_USERNAME") != ""
+}
@@ -0,0 +1,65 @@
+package secrets
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requirement нэг нууц утгад тавих шаардлага
+type Requirement struct {
+	Name      string
+	Required  bool // Заавал байх ёстой эсэх
+	MinLength int  // 0 бол шалгахгүй
+}
+
+// Жишээ тохиргоонд ихэвчлэн үлддэг утгууд
+var placeholders = map[string]bool{
+	"changeme":    true,
+	"change-me":   true,
+	"change_me":   true,
+	"secret":      true,
+	"password":    true,
+	"passw0rd":    true,
+	"default":     true,
+	"example":     true,
+	"test":        true,
+	"xxx":         true,
+	"todo":        true,
+	"admin":       true,
+	"123456":      true,
+	"your-secret": true,
+}
+
+// IsPlaceholder reports whether value looks like a default left in a sample config
+func IsPlaceholder(value string) bool {
+	v := strings.ToLower(strings.TrimSpace(value))
+	if placeholders[v] {
+		return true
+	}
+	return strings.HasPrefix(v, "<") && strings.HasSuffix(v, ">")
+}
+
+// Check returns every problem with the required secrets at once so the
+// operator can fix the configuration in one go
+func Check(requirements []Requirement) error {
+	var problems []string
+	for _, req := range requirements {
+		value, source, err := Lookup(req.Name)
+		switch {
+		case err != nil && value == "" && source != "":
+			problems = append(problems, fmt.Sprintf("%s: %s", req.Name, err))
+		case value == "":
+			if req.Required {
+				problems = append(problems, req.Name+" is not set")
+			}
+		case IsPlaceholder(value):
+			problems = append(problems, fmt.Sprintf("%s from %s is a placeholder value", req.Name, source))
+		case req.MinLength > 0 && len(value) < req.MinLength:
+			problems = append(problems, fmt.Sprintf("%s from %s is shorter than %d characters", req.Name, source, req.MinLength))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("refusing to start, fix the secrets: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptedFile нь SECRETS_KEY-ээр AES-GCM шифрлэсэн JSON {"NAME": "value"} файл
+type EncryptedFile struct {
+	values map[string]string
+}
+
+func (f *EncryptedFile) Name() string { return "encrypted-file" }
+
+func (f *EncryptedFile) Lookup(name string) (string, bool, error) {
+	value, ok := f.values[name]
+	return value, ok && value != "", nil
+}
+
+// OpenEncryptedFile decrypts a file written by Seal
+func OpenEncryptedFile(path, key string) (*EncryptedFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read secrets file: %w", err)
+	}
+	plain, err := Open(key, strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt secrets file: %w", err)
+	}
+	file := &EncryptedFile{values: map[string]string{}}
+	if err := json.Unmarshal(plain, &file.values); err != nil {
+		return nil, fmt.Errorf("parse secrets file: %w", err)
+	}
+	return file, nil
+}
+
+// Seal encrypts plain with key and returns base64(nonce|ciphertext)
+func Seal(key string, plain []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil)), nil
+}
+
+// Open reverses Seal
+func Open(key, sealed string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return nil, errors.New("malformed secrets file")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
+
+// newAEAD accepts a 32 byte key or its base64 form
+func newAEAD(key string) (cipher.AEAD, error) {
+	raw := []byte(key)
+	if len(raw) != 32 {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(decoded) != 32 {
+			return nil, errors.New("SECRETS_KEY must be 32 bytes or base64 of 32 bytes")
+		}
+		raw = decoded
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+// Provider нууц утгыг нэрээр нь олно
+type Provider interface {
+	Name() string
+	Lookup(name string) (string, bool, error)
+}
+
+// Env reads process environment variables, e.g. from docker run -e
+type Env struct{}
+
+func (Env) Name() string { return "env" }
+
+func (Env) Lookup(name string) (string, bool, error) {
+	value, ok := os.LookupEnv(name)
+	return value, ok && value != "", nil
+}
+
+// Files reads the file named by <NAME>_FILE, the Docker/Kubernetes secrets convention
+type Files struct{}
+
+func (Files) Name() string { return "file" }
+
+func (Files) Lookup(name string) (string, bool, error) {
+	path, ok := os.LookupEnv(name + "_FILE")
+	if !ok {
+		path = viper.GetString(name + "_FILE")
+	}
+	if path == "" {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// Config reads the .env file loaded by viper
+type Config struct{}
+
+func (Config) Name() string { return "config" }
+
+func (Config) Lookup(name string) (string, bool, error) {
+	value := viper.GetString(name)
+	return value, value != "", nil
+}
+
+// Chain asks each provider in order and returns the first value found
+type Chain []Provider
+
+func (c Chain) Lookup(name string) (string, string, error) {
+	for _, p := range c {
+		value, ok, err := p.Lookup(name)
+		if err != nil {
+			return "", p.Name(), err
+		}
+		if ok {
+			return value, p.Name(), nil
+		}
+	}
+	return "", "", ErrNotFound
+}
+
+var chain = Chain{Env{}, Files{}, Config{}}
+
+// Configure builds the provider chain: environment, <NAME>_FILE, the
+// encrypted SECRETS_FILE (opened with SECRETS_KEY) and finally .env.
+func Configure() error {
+	providers := Chain{Env{}, Files{}}
+	if path := viper.GetString("SECRETS_FILE"); path != "" {
+		key, _, err := providers.Lookup("SECRETS_KEY")
+		if err != nil {
+			return fmt.Errorf("SECRETS_FILE is set but SECRETS_KEY: %w", err)
+		}
+		file, err := OpenEncryptedFile(path, key)
+		if err != nil {
+			return err
+		}
+		providers = append(providers, file)
+	}
+	chain = append(providers, Config{})
+	return nil
+}
+
+// Get returns the secret or an empty string when it is not set anywhere
+func Get(name string) string {
+	value, source, err := chain.Lookup(name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		fmt.Println("Failed to read secret", name, "from", source, err)
+	}
+	return value
+}
+
+// Lookup returns the secret together with the provider it came from
+func Lookup(name string) (value, source string, err error) {
+	return chain.Lookup(name)
+}
+
+// SigningKey returns the secret name, or when it is not set a 32 byte key
+// derived from CIPHER_KEY with HKDF-SHA256 for purpose. The raw CIPHER_KEY
+// encrypts data and is never used to sign anything itself.
+func SigningKey(name, purpose string) []byte {
+	if key := Get(name); key != "" {
+		return []byte(key)
+	}
+	key, err := hkdf.Key(sha256.New, []byte(Get("CIPHER_KEY")), nil, purpose, 32)
+	if err != nil {
+		// 32 байт нь HKDF-SHA256-ийн хязгаараас бага тул алдаа гарахгүй
+		panic(err)
+	}
+	return key
+}
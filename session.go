@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mcp-server/auth"
+	"mcp-server/database"
+	"mcp-server/sessions"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultSessionIdleTimeout = 30 * time.Minute
+	defaultRolePollInterval   = time.Minute
+	defaultSessionsPerUser    = 10
+	sseKeepAlive              = 25 * time.Second
+
+	toolsListChanged = "notifications/tools/list_changed"
+)
+
+var sessionRegistry = sessions.NewRegistry()
+
+func sessionIdleTimeout() time.Duration {
+	if d := viper.GetDuration("SESSION_IDLE_TIMEOUT"); d > 0 {
+		return d
+	}
+	return defaultSessionIdleTimeout
+}
+
+// sessionsPerUser нь нэг хэрэглэгчийн нээлттэй session-ий дээд тоо, SESSION_MAX_PER_USER
+func sessionsPerUser() int {
+	if n := viper.GetInt("SESSION_MAX_PER_USER"); n > 0 {
+		return n
+	}
+	return defaultSessionsPerUser
+}
+
+// resolveSession returns the session named by the Mcp-Session-Id header.
+// Without the header only tools/list, the first call of a client, opens a
+// session and returns its id in the response header; other calls run
+// without one (nil). The user's least recently used sessions beyond
+// SESSION_MAX_PER_USER are closed.
+func resolveSession(w http.ResponseWriter, r *http.Request, caller *Caller, function string) (*sessions.Session, error) {
+	id := r.Header.Get(sessions.Header)
+	if id == "" {
+		if function != "tools/list" {
+			return nil, nil
+		}
+		session := sessionRegistry.Create(caller.ID, caller.Identity.Method, caller.Identity.Scopes)
+		sessionRegistry.Trim(caller.ID, sessionsPerUser())
+		w.Header().Set(sessions.Header, session.ID)
+		return session, nil
+	}
+	session, ok := sessionRegistry.Get(id)
+	if !ok {
+		return nil, NewToolError(http.StatusNotFound, "Session not found")
+	}
+	if session.UserID != caller.ID {
+		return nil, NewToolError(http.StatusForbidden, "Session belongs to another user")
+	}
+	session.Touch()
+	return session, nil
+}
+
+// toolsFingerprint identifies the set of tools the caller may use
+func toolsFingerprint(caller *Caller) string {
+	var names []string
+	for name := range toolScopes {
+		if caller.CanUse(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	sum := sha256.Sum256([]byte(strings.Join(names, ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// syncSessionTools records the caller's current tool set on the session and
+// notifies the client when it changed since the last tools/list.
+func syncSessionTools(session *sessions.Session, caller *Caller) {
+	if session.SetToolsHash(toolsFingerprint(caller)) {
+		fmt.Println("Tools changed for session", session.ID, "user", caller.ID)
+		session.Notify(toolsListChanged)
+	}
+}
+
+// refreshUserSessions re-evaluates the tools of every session of the user,
+// e.g. after their role changed.
+func refreshUserSessions(userID uint) {
+	userSessions := sessionRegistry.ForUser(userID)
+	if len(userSessions) == 0 {
+		return
+	}
+	var user database.User
+	if err := database.DB.Preload("Team").Preload("Role").First(&user, userID).Error; err != nil {
+		fmt.Println("Failed to reload session user", userID, err)
+		return
+	}
+	for _, session := range userSessions {
+		caller, err := NewCaller(&auth.Identity{User: &user, Method: session.Method, Scopes: session.Scopes})
+		if err != nil {
+			fmt.Println("Failed to resolve session permissions", session.ID, err)
+			continue
+		}
+		syncSessionTools(session, caller)
+	}
+}
+
+// notifyAllSessions tells every client to refetch tools/list, e.g. when an
+// enum in a tool schema changed.
+func notifyAllSessions() {
+	for _, session := range sessionRegistry.All() {
+		session.Notify(toolsListChanged)
+	}
+}
+
+// WatchSessions expires idle sessions and picks up role changes made outside
+// this server (SESSION_ROLE_POLL_INTERVAL).
+func WatchSessions() {
+	interval := viper.GetDuration("SESSION_ROLE_POLL_INTERVAL")
+	if interval <= 0 {
+		interval = defaultRolePollInterval
+	}
+	for range time.Tick(interval) {
+		sessionRegistry.Expire(sessionIdleTimeout())
+		seen := map[uint]bool{}
+		for _, session := range sessionRegistry.All() {
+			if !seen[session.UserID] {
+				seen[session.UserID] = true
+				refreshUserSessions(session.UserID)
+			}
+		}
+	}
+}
+
+// SessionEventsHandler GET /call-function, session-ий мэдэгдлийг SSE-ээр дамжуулна
+func SessionEventsHandler(w http.ResponseWriter, r *http.Request) {
+	caller, err := callerFromRequest(r)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+	if r.Header.Get(sessions.Header) == "" {
+		http.Error(w, sessions.Header+" header is required", http.StatusBadRequest)
+		return
+	}
+	session, err := resolveSession(w, r, caller, "")
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case message := <-session.Events():
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", message)
+			flusher.Flush()
+		case <-keepAlive.C:
+			session.Touch()
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-session.Done():
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// SessionDeleteHandler DELETE /call-function, клиент session-ээ хаана
+func SessionDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	caller, err := callerFromRequest(r)
+	if err != nil {
+		writeToolError(w, err)
+		return
+	}
+	session, ok := sessionRegistry.Get(r.Header.Get(sessions.Header))
+	if !ok || session.UserID != caller.ID {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	sessionRegistry.Delete(session.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,169 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Header MCP Streamable HTTP-ийн session толгой
+const Header = "Mcp-Session-Id"
+
+// Session нэг MCP клиентийн холболт
+type Session struct {
+	ID        string
+	UserID    uint
+	Method    string   // Нэвтэрсэн арга
+	Scopes    []string // Токены scope
+	CreatedAt time.Time
+
+	mu        sync.Mutex
+	toolsHash string
+	lastSeen  time.Time
+	events    chan []byte
+	done      chan struct{}
+}
+
+// Registry идэвхтэй session-уудын бүртгэл
+type Registry struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+func NewRegistry() *Registry {
+	return &Registry{sessions: map[string]*Session{}}
+}
+
+// Create opens a session for the user
+func (r *Registry) Create(userID uint, method string, scopes []string) *Session {
+	random := make([]byte, 16)
+	rand.Read(random)
+	now := time.Now()
+	s := &Session{
+		ID:        hex.EncodeToString(random),
+		UserID:    userID,
+		Method:    method,
+		Scopes:    scopes,
+		CreatedAt: now,
+		lastSeen:  now,
+		events:    make(chan []byte, 16),
+		done:      make(chan struct{}),
+	}
+	r.mu.Lock()
+	r.sessions[s.ID] = s
+	r.mu.Unlock()
+	return s
+}
+
+func (r *Registry) Get(id string) (*Session, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.sessions[id]
+	return s, ok
+}
+
+func (r *Registry) Delete(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[id]; ok {
+		close(s.done)
+		delete(r.sessions, id)
+	}
+}
+
+// All returns a snapshot of the open sessions
+func (r *Registry) All() []*Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]*Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		list = append(list, s)
+	}
+	return list
+}
+
+// ForUser returns the open sessions of a user
+func (r *Registry) ForUser(userID uint) []*Session {
+	var list []*Session
+	for _, s := range r.All() {
+		if s.UserID == userID {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// Expire closes sessions idle for longer than idle
+func (r *Registry) Expire(idle time.Duration) {
+	for _, s := range r.All() {
+		s.mu.Lock()
+		expired := time.Since(s.lastSeen) > idle
+		s.mu.Unlock()
+		if expired {
+			r.Delete(s.ID)
+		}
+	}
+}
+
+// Trim closes the least recently used sessions of the user until at most
+// keep remain
+func (r *Registry) Trim(userID uint, keep int) {
+	list := r.ForUser(userID)
+	if len(list) <= keep {
+		return
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastSeen().Before(list[j].LastSeen())
+	})
+	for _, s := range list[:len(list)-keep] {
+		r.Delete(s.ID)
+	}
+}
+
+// LastSeen returns when the session was last used
+func (s *Session) LastSeen() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSeen
+}
+
+// Touch marks the session as used
+func (s *Session) Touch() {
+	s.mu.Lock()
+	s.lastSeen = time.Now()
+	s.mu.Unlock()
+}
+
+// SetToolsHash stores the fingerprint of the tool list the client has seen
+// and reports whether it differs from the previous one.
+func (s *Session) SetToolsHash(hash string) (changed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed = s.toolsHash != "" && s.toolsHash != hash
+	s.toolsHash = hash
+	return changed
+}
+
+// Events returns the stream of JSON-RPC messages queued for the client
+func (s *Session) Events() <-chan []byte {
+	return s.events
+}
+
+// Done is closed when the session is deleted
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Notify queues a JSON-RPC notification. When the client is not listening
+// and the buffer is full the notification is dropped; list_changed only
+// tells the client to refetch, so a later one carries the same meaning.
+func (s *Session) Notify(method string) {
+	message, _ := json.Marshal(map[string]string{"jsonrpc": "2.0", "method": method})
+	select {
+	case s.events <- message:
+	default:
+	}
+}
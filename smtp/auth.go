@@ -0,0 +1,114 @@
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// SMTP_AUTH утгууд
+const (
+	AuthAuto    = "auto" // Серверийн санал болгосноос сонгоно
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCRAMMD5 = "cram-md5"
+	AuthNone    = "none"
+)
+
+type loginAuth struct {
+	username, password string
+}
+
+func LoginAuth(username, password string) smtp.Auth {
+	return &loginAuth{username: username, password: password}
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "LOGIN", []byte(a.username), nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		switch strings.ToLower(string(fromServer)) {
+		case "username:":
+			return []byte(a.username), nil
+		case "password:":
+			return []byte(a.password), nil
+		default:
+			return nil, errors.New("unknown from server")
+		}
+	}
+	return nil, nil
+}
+
+// isLocalhost matches the hosts net/smtp.PlainAuth accepts without TLS
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// cleartextAuth reports whether the mechanism sends the password itself
+func cleartextAuth(method string) bool {
+	return method == AuthPlain || method == AuthLogin
+}
+
+// chooseAuth picks a mechanism the server offers. Over an encrypted
+// connection PLAIN is preferred. Without TLS CRAM-MD5 comes first so the
+// password is not sent, and PLAIN/LOGIN are only used towards localhost,
+// the same rule net/smtp.PlainAuth enforces.
+func chooseAuth(offered string, encrypted, local bool) string {
+	supported := map[string]bool{}
+	for _, m := range strings.Fields(strings.ToLower(offered)) {
+		supported[m] = true
+	}
+	order := []string{AuthPlain, AuthLogin, AuthCRAMMD5}
+	switch {
+	case !encrypted && local:
+		order = []string{AuthCRAMMD5, AuthPlain, AuthLogin}
+	case !encrypted:
+		order = []string{AuthCRAMMD5}
+	}
+	for _, m := range order {
+		if supported[m] {
+			return m
+		}
+	}
+	return ""
+}
+
+// authenticate logs in with SMTP_AUTH, skipped when there is no username
+func (c *Client) authenticate(client *smtp.Client) error {
+	if c.Username == "" || c.AuthMethod == AuthNone {
+		return nil
+	}
+	ok, offered := client.Extension("AUTH")
+	if !ok {
+		return errors.New("auth: server does not support AUTH")
+	}
+	_, encrypted := client.TLSConnectionState()
+	method := c.AuthMethod
+	if method == "" || method == AuthAuto {
+		if method = chooseAuth(offered, encrypted, isLocalhost(c.SmtpHost)); method == "" {
+			return fmt.Errorf("auth: no usable mechanism in %q, PLAIN and LOGIN need TLS or a localhost relay", offered)
+		}
+	}
+	if cleartextAuth(method) && !encrypted && !isLocalhost(c.SmtpHost) {
+		return fmt.Errorf("auth %s: refusing to send the password without TLS", method)
+	}
+
+	var auth smtp.Auth
+	switch method {
+	case AuthPlain:
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.SmtpHost)
+	case AuthLogin:
+		auth = LoginAuth(c.Username, c.Password)
+	case AuthCRAMMD5:
+		auth = smtp.CRAMMD5Auth(c.Username, c.Password)
+	default:
+		return fmt.Errorf("unknown SMTP_AUTH %q", method)
+	}
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("auth %s: %w", method, err)
+	}
+	return nil
+}
@@ -1,195 +1,216 @@
 package smtp
 
 import (
-	"bytes"
 	"crypto/tls"
 	"errors"
 	"fmt"
-	"html/template"
-	"log"
-	"mime/multipart"
 	"net"
 	"net/smtp"
 	"strings"
+	"sync"
+	"time"
+
+	"mcp-server/dates"
+	"mcp-server/secrets"
 
 	"github.com/spf13/viper"
 )
 
-type Client struct {
-	From     string
-	Password string
-	SmtpHost string
-	SmtpPort string
+// Загварт огноог байгууллагын цагийн бүсээр харуулна
+var templateFuncs = map[string]interface{}{
+	"date":     formatWith(dates.FormatDate),
+	"datetime": formatWith(dates.FormatDateTime),
 }
 
-func CreateClient() *Client {
-	return &Client{
-		From:     viper.GetString("SMTP_FROM"),
-		Password: "yB$9f66v1",
-		SmtpHost: viper.GetString("SMTP_HOST"),
-		SmtpPort: viper.GetString("SMTP_PORT"),
+func formatWith(format func(time.Time) string) func(interface{}) string {
+	return func(v interface{}) string {
+		switch t := v.(type) {
+		case time.Time:
+			return format(t)
+		case *time.Time:
+			if t != nil {
+				return format(*t)
+			}
+			return ""
+		case string:
+			// JSON-оос ирсэн огноо (урьдчилан харах, outbox)
+			if resolved, err := dates.Parse(t); err == nil {
+				return format(resolved.Time)
+			}
+			return t
+		default:
+			return fmt.Sprint(v)
+		}
 	}
 }
 
-type loginAuth struct {
-	username, password string
-}
+// SMTP_TLS_MODE утгууд
+const (
+	TLSStartTLS = "starttls" // Энгийн холболтоор эхлээд STARTTLS, 587
+	TLSImplicit = "smtps"    // Шууд TLS холболт, 465
+	TLSNone     = "none"     // Шифрлэлтгүй, локал relay
+)
 
-func LoginAuth() smtp.Auth {
-	return &loginAuth{username: viper.GetString("SMTP_USERNAME"), password: "yB$9f66v1"}
-}
+const (
+	defaultDialTimeout = 10 * time.Second
+	defaultTimeout     = 30 * time.Second
+	defaultPoolSize    = 2
+	defaultIdleTimeout = 30 * time.Second
+)
 
-func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
-	return "LOGIN", []byte(a.username), nil
+type Client struct {
+	From       string
+	Username   string
+	Password   string
+	SmtpHost   string
+	SmtpPort   string
+	TLSMode    string // starttls, smtps, none
+	AuthMethod string // auto, plain, login, cram-md5, none
+	SkipVerify bool   // Өөрөө гарын үсэг зурсан гэрчилгээтэй тест relay-д
+
+	DialTimeout time.Duration // Холбогдох хугацаа
+	Timeout     time.Duration // Нэг мессеж илгээх уншилт/бичилтийн хугацаа
+	PoolSize    int           // Дахин ашиглахаар хадгалах сул холболтын тоо
+	IdleTimeout time.Duration // Сул холболтыг хаах хугацаа
+
+	mu   sync.Mutex
+	idle []*conn
 }
 
-func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
-	if more {
-		switch string(fromServer) {
-		case "Username:":
-			return []byte(a.username), nil
-		case "Password:":
-			return []byte(a.password), nil
-		default:
-			return nil, errors.New("unknown from server")
+func CreateClient() *Client {
+	c := &Client{
+		From:        viper.GetString("SMTP_FROM"),
+		Username:    viper.GetString("SMTP_USERNAME"),
+		Password:    secrets.Get("SMTP_PASS"),
+		SmtpHost:    viper.GetString("SMTP_HOST"),
+		SmtpPort:    viper.GetString("SMTP_PORT"),
+		TLSMode:     strings.ToLower(viper.GetString("SMTP_TLS_MODE")),
+		AuthMethod:  strings.ToLower(viper.GetString("SMTP_AUTH")),
+		SkipVerify:  viper.GetBool("SMTP_TLS_SKIP_VERIFY"),
+		DialTimeout: viper.GetDuration("SMTP_DIAL_TIMEOUT"),
+		Timeout:     viper.GetDuration("SMTP_TIMEOUT"),
+		PoolSize:    defaultPoolSize,
+		IdleTimeout: viper.GetDuration("SMTP_POOL_IDLE_TIMEOUT"),
+	}
+	if viper.IsSet("SMTP_POOL_SIZE") {
+		c.PoolSize = viper.GetInt("SMTP_POOL_SIZE")
+	}
+	if c.TLSMode == "" {
+		// 465 бол implicit TLS, бусад нь STARTTLS
+		c.TLSMode = TLSStartTLS
+		if c.SmtpPort == "465" {
+			c.TLSMode = TLSImplicit
 		}
 	}
-	return nil, nil
+	return c
 }
 
-func (c Client) Send(input EmailInput, param map[string]interface{}) error {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println("Error Email Sending", r)
-		}
-	}()
-
-	fmt.Println(input)
-
-	templatePath := "files/email-template/" + input.Template + ".html"
-	t, tmpErr := template.ParseFiles(templatePath)
-	if tmpErr != nil {
-		fmt.Println("Template error", tmpErr.Error())
-		return tmpErr
+// Validate rejects settings that can never deliver, e.g. SMTP_AUTH=plain
+// with SMTP_TLS_MODE=none towards a remote server: the password would go
+// out in clear and net/smtp refuses PLAIN there anyway.
+func (c *Client) Validate() error {
+	switch c.TLSMode {
+	case TLSStartTLS, TLSImplicit, TLSNone, "tls", "ssl":
+	default:
+		return fmt.Errorf("unknown SMTP_TLS_MODE %q", c.TLSMode)
 	}
-
-	tlsconfig := &tls.Config{
-		ServerName: c.SmtpHost,
-		MinVersion: tls.VersionTLS12,
+	switch c.AuthMethod {
+	case "", AuthAuto, AuthPlain, AuthLogin, AuthCRAMMD5, AuthNone:
+	default:
+		return fmt.Errorf("unknown SMTP_AUTH %q", c.AuthMethod)
 	}
+	if c.TLSMode == TLSNone && c.Username != "" && cleartextAuth(c.AuthMethod) && !isLocalhost(c.SmtpHost) {
+		return fmt.Errorf("SMTP_AUTH=%s needs TLS: use SMTP_TLS_MODE=starttls or smtps, SMTP_AUTH=cram-md5, or a relay on localhost", c.AuthMethod)
+	}
+	return nil
+}
 
-	conn, err := net.Dial("tcp", c.SmtpHost+":"+c.SmtpPort)
+// Send renders the template and delivers it through this client
+func (c *Client) Send(input EmailInput, param map[string]interface{}) error {
+	msg, err := Render(c.From, input, param)
 	if err != nil {
-		fmt.Println("TLS DIAL ERROR", err.Error())
-		log.Panic(err)
 		return err
 	}
+	return c.Deliver(msg)
+}
+
+// Deliver writes msg to the SMTP server over a pooled connection. Errors are
+// returned to the caller, the outbox decides whether to retry.
+func (c *Client) Deliver(msg *Message) error {
+	fmt.Println("Sending mail", msg.To, msg.Subject)
 
-	client, err := smtp.NewClient(conn, c.SmtpHost)
+	cn, err := c.acquire()
 	if err != nil {
-		fmt.Println("New client error", err.Error())
-		log.Panic(err)
 		return err
 	}
-
-	if err = client.StartTLS(tlsconfig); err != nil {
-		fmt.Println("StartTLS error", err.Error())
-		log.Panic(err)
+	if err := cn.send(msg, c.timeout()); err != nil {
+		cn.close()
 		return err
 	}
+	c.release(cn)
 
-	auth := LoginAuth()
+	fmt.Println("Mail sent successfully")
+	return nil
+}
 
-	if err = client.Auth(auth); err != nil {
-		fmt.Println("Client auth", err.Error())
-		log.Panic(err.Error())
-		return err
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
 	}
+	return defaultTimeout
+}
 
-	if err = client.Mail(c.From); err != nil {
-		fmt.Println("Client mail", err.Error())
-		log.Panic(err)
-		return err
+func (c *Client) tlsConfig() *tls.Config {
+	return &tls.Config{
+		ServerName:         c.SmtpHost,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.SkipVerify,
 	}
+}
 
-	if err = client.Rcpt(input.Email); err != nil {
-		fmt.Println("RCPT ERROR", err.Error())
-		log.Panic(err)
-		return err
+// dial opens a connection in the configured TLS mode and authenticates
+func (c *Client) dial() (*conn, error) {
+	dialer := &net.Dialer{Timeout: c.DialTimeout}
+	if dialer.Timeout <= 0 {
+		dialer.Timeout = defaultDialTimeout
 	}
+	addr := net.JoinHostPort(c.SmtpHost, c.SmtpPort)
 
-	if len(input.MultiBcc) > 0 {
-		for _, bccEmail := range input.MultiBcc {
-			if bccEmail != "" {
-				if err = client.Rcpt(bccEmail); err != nil {
-					fmt.Println("MULTI BCC RCPT ERROR for", bccEmail, err.Error())
-					log.Panic(err)
-					return err
-				}
-			}
-		}
+	var raw net.Conn
+	var err error
+	switch c.TLSMode {
+	case TLSImplicit, "tls", "ssl":
+		raw, err = tls.DialWithDialer(dialer, "tcp", addr, c.tlsConfig())
+	case TLSStartTLS, TLSNone:
+		raw, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown SMTP_TLS_MODE %q", c.TLSMode)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	raw.SetDeadline(time.Now().Add(c.timeout()))
 
-	w, err := client.Data()
+	client, err := smtp.NewClient(raw, c.SmtpHost)
 	if err != nil {
-		log.Panic(err)
-		return err
+		raw.Close()
+		return nil, fmt.Errorf("new client: %w", err)
 	}
+	cn := &conn{raw: raw, client: client}
 
-	var body bytes.Buffer
-	writer := multipart.NewWriter(&body)
-	headers := map[string]string{
-		"From":         fmt.Sprintf("FIBO GLOBAL <%s>", c.From),
-		"To":           input.Email,
-		"MIME-Version": "1.0",
-		"Content-Type": "multipart/mixed; boundary=" + writer.Boundary(),
-		"Reply-To":     c.From,
-		"Return-Path":  c.From,
-		"X-Mailer":     "Go SMTP Client",
-		"Subject":      "test",
-	}
-
-	if len(input.MultiBcc) > 0 {
-		validBccs := []string{}
-		for _, bcc := range input.MultiBcc {
-			if bcc != "" {
-				validBccs = append(validBccs, bcc)
-			}
+	if c.TLSMode == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			cn.close()
+			return nil, errors.New("starttls: server does not support STARTTLS, set SMTP_TLS_MODE")
 		}
-		if len(validBccs) > 0 {
-			headers["Bcc"] = strings.Join(validBccs, ", ")
+		if err := client.StartTLS(c.tlsConfig()); err != nil {
+			cn.close()
+			return nil, fmt.Errorf("starttls: %w", err)
 		}
 	}
-
-	for key, value := range headers {
-		body.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
-	}
-	body.WriteString("\r\n")
-
-	htmlPart, _ := writer.CreatePart(map[string][]string{
-		"Content-Type": {"text/html; charset=\"UTF-8\""},
-	})
-	if err := t.Execute(htmlPart, param); err != nil {
-		fmt.Println("HTML template execution error:", err)
-		return err
-	}
-
-	writer.Close()
-
-	_, err = w.Write(body.Bytes())
-	if err != nil {
-		log.Panic(err)
-		return err
+	if err := c.authenticate(client); err != nil {
+		cn.close()
+		return nil, err
 	}
-
-	err = w.Close()
-	if err != nil {
-		log.Panic(err)
-		return err
-	}
-
-	client.Quit()
-
-	log.Println("Mail sent successfully")
-	return nil
+	return cn, nil
 }
@@ -0,0 +1,511 @@
+package smtp
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testUser = "noreply@example.mn"
+	testPass = "s3cret-pass"
+)
+
+// fakeServer is a minimal ESMTP server on 127.0.0.1 that records what the
+// client did. It understands EHLO, STARTTLS, AUTH PLAIN/LOGIN/CRAM-MD5,
+// MAIL, RCPT, DATA, NOOP, RSET and QUIT.
+type fakeServer struct {
+	t        *testing.T
+	ln       net.Listener
+	tls      *tls.Config
+	implicit bool   // Холболт шууд TLS
+	starttls bool   // EHLO-д STARTTLS санал болгоно
+	auth     string // EHLO-д санал болгох AUTH механизмууд
+	// Тест тохируулах зан төлөв
+	silent        bool          // Мэндчилгээ илгээхгүй
+	stallData     time.Duration // DATA-ийн хариуг хойшлуулна
+	closeAfterMsg bool          // Мессеж бүрийн дараа холболтыг хаана
+
+	mu       sync.Mutex
+	conns    int
+	mechs    []string // Амжилттай нэвтэрсэн механизмууд
+	messages []fakeMessage
+}
+
+type fakeMessage struct {
+	from      string
+	rcpt      []string
+	data      string
+	encrypted bool
+	authed    bool
+}
+
+func newFakeServer(t *testing.T, configure func(*fakeServer)) *fakeServer {
+	t.Helper()
+	s := &fakeServer{t: t, tls: &tls.Config{Certificates: []tls.Certificate{selfSigned(t)}}}
+	if configure != nil {
+		configure(s)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.implicit {
+		ln = tls.NewListener(ln, s.tls)
+	}
+	s.ln = ln
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) client(mode string) *Client {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return &Client{
+		From:        testUser,
+		Username:    testUser,
+		Password:    testPass,
+		SmtpHost:    host,
+		SmtpPort:    port,
+		TLSMode:     mode,
+		AuthMethod:  AuthAuto,
+		SkipVerify:  true,
+		DialTimeout: 2 * time.Second,
+		Timeout:     2 * time.Second,
+		PoolSize:    2,
+		IdleTimeout: time.Minute,
+	}
+}
+
+func (s *fakeServer) serve() {
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *fakeServer) stats() (conns int, mechs []string, messages []fakeMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, append([]string{}, s.mechs...), append([]fakeMessage{}, s.messages...)
+}
+
+func (s *fakeServer) handle(c net.Conn) {
+	defer c.Close()
+	if s.silent {
+		time.Sleep(5 * time.Second)
+		return
+	}
+	encrypted := s.implicit
+	r := bufio.NewReader(c)
+	reply := func(lines ...string) {
+		fmt.Fprint(c, strings.Join(lines, "\r\n")+"\r\n")
+	}
+	readLine := func() (string, bool) {
+		line, err := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err == nil
+	}
+
+	reply("220 fake ESMTP")
+	var msg fakeMessage
+	authed := false
+	for {
+		line, ok := readLine()
+		if !ok {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"250-fake"}
+			if s.starttls && !encrypted {
+				lines = append(lines, "250-STARTTLS")
+			}
+			if s.auth != "" {
+				lines = append(lines, "250-AUTH "+s.auth)
+			}
+			reply(append(lines, "250 8BITMIME")...)
+		case "STARTTLS":
+			reply("220 ready")
+			tc := tls.Server(c, s.tls)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			c, r, encrypted = tc, bufio.NewReader(tc), true
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			if s.authenticate(strings.ToUpper(mech), initial, reply, readLine) {
+				authed = true
+				s.mu.Lock()
+				s.mechs = append(s.mechs, strings.ToUpper(mech))
+				s.mu.Unlock()
+				reply("235 ok")
+			} else {
+				reply("535 authentication failed")
+			}
+		case "MAIL":
+			msg = fakeMessage{from: arg, encrypted: encrypted, authed: authed}
+			reply("250 ok")
+		case "RCPT":
+			msg.rcpt = append(msg.rcpt, arg)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, ok := readLine()
+				if !ok {
+					return
+				}
+				if l == "." {
+					break
+				}
+				data.WriteString(l + "\r\n")
+			}
+			msg.data = data.String()
+			if s.stallData > 0 {
+				time.Sleep(s.stallData)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+			if s.closeAfterMsg {
+				return
+			}
+		case "NOOP", "RSET":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+func (s *fakeServer) authenticate(mech, initial string, reply func(...string), readLine func() (string, bool)) bool {
+	decode := func(v string) string {
+		b, _ := base64.StdEncoding.DecodeString(v)
+		return string(b)
+	}
+	prompt := func(text string) string {
+		reply("334 " + base64.StdEncoding.EncodeToString([]byte(text)))
+		line, _ := readLine()
+		return decode(line)
+	}
+	switch mech {
+	case "PLAIN":
+		resp := decode(initial)
+		if initial == "" {
+			resp = prompt("")
+		}
+		parts := strings.Split(resp, "\x00")
+		return len(parts) == 3 && parts[1] == testUser && parts[2] == testPass
+	case "LOGIN":
+		user := decode(initial)
+		if initial == "" {
+			user = prompt("Username:")
+		}
+		return user == testUser && prompt("Password:") == testPass
+	case "CRAM-MD5":
+		challenge := "<1234.5678@fake>"
+		user, digest, _ := strings.Cut(prompt(challenge), " ")
+		mac := hmac.New(md5.New, []byte(testPass))
+		mac.Write([]byte(challenge))
+		return user == testUser && digest == hex.EncodeToString(mac.Sum(nil))
+	}
+	return false
+}
+
+func selfSigned(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func testMessage(n int) *Message {
+	return &Message{
+		From:    testUser,
+		To:      []string{"bat@example.mn"},
+		Bcc:     []string{"audit@example.mn"},
+		Subject: fmt.Sprintf("Чөлөөний хүсэлт #%d", n),
+		Text:    "hello",
+	}
+}
+
+func TestClientModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		configure func(*fakeServer)
+		encrypted bool
+	}{
+		{"smtps", TLSImplicit, func(s *fakeServer) { s.implicit, s.auth = true, "PLAIN LOGIN" }, true},
+		{"starttls", TLSStartTLS, func(s *fakeServer) { s.starttls, s.auth = true, "PLAIN LOGIN" }, true},
+		{"none", TLSNone, func(s *fakeServer) { s.auth = "CRAM-MD5" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, tt.configure)
+			client := server.client(tt.mode)
+			defer client.Close()
+			if err := client.Deliver(testMessage(1)); err != nil {
+				t.Fatal(err)
+			}
+			_, _, messages := server.stats()
+			if len(messages) != 1 {
+				t.Fatalf("server got %d messages", len(messages))
+			}
+			got := messages[0]
+			if got.encrypted != tt.encrypted || !got.authed {
+				t.Errorf("encrypted=%v authed=%v, want encrypted=%v authed", got.encrypted, got.authed, tt.encrypted)
+			}
+			if !strings.HasPrefix(got.from, "FROM:<"+testUser+">") {
+				t.Errorf("MAIL %s", got.from)
+			}
+			if len(got.rcpt) != 2 || !strings.Contains(got.rcpt[1], "audit@example.mn") {
+				t.Errorf("RCPT %v, want To and Bcc in the envelope", got.rcpt)
+			}
+			if strings.Contains(got.data, "audit@example.mn") || !strings.Contains(got.data, "Subject: =?UTF-8?b?") {
+				t.Errorf("unexpected DATA:\n%s", got.data)
+			}
+		})
+	}
+}
+
+func TestClientStartTLSRequired(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) { s.auth = "PLAIN" })
+	client := server.client(TLSStartTLS)
+	err := client.Deliver(testMessage(1))
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Deliver = %v, want a STARTTLS error", err)
+	}
+	if _, _, messages := server.stats(); len(messages) != 0 {
+		t.Errorf("message sent without STARTTLS")
+	}
+}
+
+func TestClientAuthSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		offered string
+		method  string
+		want    string // Сервер дээр ашигласан механизм, хоосон бол алдаа
+	}{
+		{"tls prefers plain", TLSStartTLS, "CRAM-MD5 LOGIN PLAIN", AuthAuto, "PLAIN"},
+		{"tls login", TLSStartTLS, "LOGIN CRAM-MD5", AuthAuto, "LOGIN"},
+		{"tls cram-md5", TLSStartTLS, "CRAM-MD5", AuthAuto, "CRAM-MD5"},
+		{"smtps plain", TLSImplicit, "PLAIN", AuthAuto, "PLAIN"},
+		{"plaintext prefers cram-md5", TLSNone, "PLAIN LOGIN CRAM-MD5", AuthAuto, "CRAM-MD5"},
+		// 127.0.0.1 нь localhost тул PLAIN TLS-гүй ч зөвшөөрөгдөнө
+		{"plaintext plain to localhost", TLSNone, "PLAIN", AuthAuto, "PLAIN"},
+		{"plaintext login to localhost", TLSNone, "LOGIN", AuthAuto, "LOGIN"},
+		{"forced login", TLSStartTLS, "PLAIN LOGIN", AuthLogin, "LOGIN"},
+		{"forced cram-md5", TLSStartTLS, "PLAIN CRAM-MD5", AuthCRAMMD5, "CRAM-MD5"},
+		{"nothing usable", TLSStartTLS, "XOAUTH2", AuthAuto, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, func(s *fakeServer) {
+				s.implicit = tt.mode == TLSImplicit
+				s.starttls = tt.mode == TLSStartTLS
+				s.auth = tt.offered
+			})
+			client := server.client(tt.mode)
+			client.AuthMethod = tt.method
+			defer client.Close()
+			err := client.Deliver(testMessage(1))
+			_, mechs, _ := server.stats()
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("Deliver succeeded with %v", mechs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(mechs) != 1 || mechs[0] != tt.want {
+				t.Errorf("authenticated with %v, want %s", mechs, tt.want)
+			}
+		})
+	}
+}
+
+func TestChooseAuth(t *testing.T) {
+	tests := []struct {
+		offered          string
+		encrypted, local bool
+		want             string
+	}{
+		{"PLAIN LOGIN CRAM-MD5", true, false, AuthPlain},
+		{"LOGIN CRAM-MD5", true, false, AuthLogin},
+		{"PLAIN LOGIN CRAM-MD5", false, true, AuthCRAMMD5},
+		{"PLAIN LOGIN", false, true, AuthPlain},
+		{"PLAIN LOGIN CRAM-MD5", false, false, AuthCRAMMD5},
+		// Алсын сервер рүү нууц үгийг ил илгээхгүй
+		{"PLAIN LOGIN", false, false, ""},
+		{"", true, false, ""},
+	}
+	for _, tt := range tests {
+		if got := chooseAuth(tt.offered, tt.encrypted, tt.local); got != tt.want {
+			t.Errorf("chooseAuth(%q, encrypted=%v, local=%v) = %q, want %q", tt.offered, tt.encrypted, tt.local, got, tt.want)
+		}
+	}
+}
+
+func TestClientValidate(t *testing.T) {
+	tests := []struct {
+		mode, auth, host string
+		ok               bool
+	}{
+		{TLSNone, AuthPlain, "mail.example.mn", false},
+		{TLSNone, AuthLogin, "mail.example.mn", false},
+		{TLSNone, AuthPlain, "127.0.0.1", true},
+		{TLSNone, AuthPlain, "localhost", true},
+		{TLSNone, AuthCRAMMD5, "mail.example.mn", true},
+		{TLSNone, AuthAuto, "mail.example.mn", true},
+		{TLSStartTLS, AuthPlain, "mail.example.mn", true},
+		{TLSImplicit, AuthLogin, "mail.example.mn", true},
+		{"ssl3", AuthAuto, "mail.example.mn", false},
+		{TLSStartTLS, "xoauth2", "mail.example.mn", false},
+	}
+	for _, tt := range tests {
+		c := &Client{Username: testUser, SmtpHost: tt.host, TLSMode: tt.mode, AuthMethod: tt.auth}
+		if err := c.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate(mode=%s auth=%s host=%s) = %v, want ok=%v", tt.mode, tt.auth, tt.host, err, tt.ok)
+		}
+	}
+}
+
+func TestClientTimeouts(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		configure func(*fakeServer)
+		setup     func(*Client)
+	}{
+		// Сервер TLS handshake хийхгүй тул smtps холболт DialTimeout-д унана
+		{"dial", TLSImplicit, func(s *fakeServer) { s.silent = true }, func(c *Client) { c.DialTimeout, c.Timeout = 200*time.Millisecond, time.Minute }},
+		// Мэндчилгээ ирэхгүй: Timeout
+		{"greeting", TLSNone, func(s *fakeServer) { s.silent = true }, func(c *Client) { c.Timeout = 200 * time.Millisecond }},
+		// DATA-ийн хариу удаан: Timeout
+		{"command", TLSNone, func(s *fakeServer) { s.auth, s.stallData = "CRAM-MD5", 3*time.Second }, func(c *Client) { c.Timeout = 300 * time.Millisecond }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t, tt.configure)
+			client := server.client(tt.mode)
+			tt.setup(client)
+			start := time.Now()
+			err := client.Deliver(testMessage(1))
+			if err == nil {
+				t.Fatal("Deliver succeeded, want a timeout")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Deliver returned after %s: %v", elapsed, err)
+			}
+			if ne, ok := asNetError(err); !ok || !ne.Timeout() {
+				t.Errorf("Deliver = %v, want a timeout error", err)
+			}
+		})
+	}
+}
+
+func asNetError(err error) (net.Error, bool) {
+	for err != nil {
+		if ne, ok := err.(net.Error); ok {
+			return ne, true
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return nil, false
+		}
+		err = u.Unwrap()
+	}
+	return nil, false
+}
+
+func TestClientReusesConnection(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) { s.starttls, s.auth = true, "PLAIN" })
+	client := server.client(TLSStartTLS)
+	defer client.Close()
+	for i := 1; i <= 3; i++ {
+		if err := client.Deliver(testMessage(i)); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+	conns, mechs, messages := server.stats()
+	if conns != 1 || len(mechs) != 1 || len(messages) != 3 {
+		t.Errorf("conns=%d logins=%d messages=%d, want one connection and login for three messages", conns, len(mechs), len(messages))
+	}
+}
+
+func TestClientRedialsClosedConnection(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) { s.auth, s.closeAfterMsg = "CRAM-MD5", true })
+	client := server.client(TLSNone)
+	defer client.Close()
+	for i := 1; i <= 3; i++ {
+		if err := client.Deliver(testMessage(i)); err != nil {
+			t.Fatalf("message %d after the server closed the connection: %v", i, err)
+		}
+	}
+	conns, _, messages := server.stats()
+	if conns != 3 || len(messages) != 3 {
+		t.Errorf("conns=%d messages=%d, want a new connection per message", conns, len(messages))
+	}
+}
+
+func TestClientDropsIdleConnection(t *testing.T) {
+	server := newFakeServer(t, func(s *fakeServer) { s.auth = "CRAM-MD5" })
+	client := server.client(TLSNone)
+	client.IdleTimeout = 50 * time.Millisecond
+	defer client.Close()
+	if err := client.Deliver(testMessage(1)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if err := client.Deliver(testMessage(2)); err != nil {
+		t.Fatal(err)
+	}
+	if conns, _, _ := server.stats(); conns != 2 {
+		t.Errorf("conns=%d, want the idle connection replaced", conns)
+	}
+}
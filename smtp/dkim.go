@@ -0,0 +1,242 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"mcp-server/secrets"
+
+	"github.com/spf13/viper"
+)
+
+// Гарын үсэг зурах толгойнууд, DKIM_HEADERS-ээр солино. From заавал орно.
+var defaultDKIMHeaders = []string{
+	"from", "to", "cc", "subject", "date", "message-id", "reply-to", "mime-version", "content-type",
+}
+
+// DKIM нь гарч буй имэйлд DKIM-Signature толгой нэмнэ, RFC 6376, RFC 8463
+type DKIM struct {
+	Domain   string
+	Selector string
+	Headers  []string // Жижиг үсгээр
+	signer   crypto.Signer
+}
+
+// DKIMSigner нь ConfigureDKIM тохируулсан гарын үсэг, nil бол гарын үсэггүй
+var DKIMSigner *DKIM
+
+// ConfigureDKIM enables signing when DKIM_DOMAIN is set. The PEM key comes
+// from the DKIM_PRIVATE_KEY secret (or DKIM_PRIVATE_KEY_FILE).
+func ConfigureDKIM() (*DKIM, error) {
+	domain := viper.GetString("DKIM_DOMAIN")
+	if domain == "" {
+		DKIMSigner = nil
+		return nil, nil
+	}
+	selector := viper.GetString("DKIM_SELECTOR")
+	if selector == "" {
+		return nil, errors.New("DKIM_SELECTOR is required when DKIM_DOMAIN is set")
+	}
+	key := secrets.Get("DKIM_PRIVATE_KEY")
+	if key == "" {
+		return nil, errors.New("DKIM_PRIVATE_KEY is required when DKIM_DOMAIN is set")
+	}
+	d, err := NewDKIM(domain, selector, []byte(key), viper.GetString("DKIM_HEADERS"))
+	if err != nil {
+		return nil, err
+	}
+	DKIMSigner = d
+	fmt.Println("DKIM signing as", selector+"._domainkey."+domain, d.Algorithm())
+	return d, nil
+}
+
+// NewDKIM parses an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) PEM key.
+// headers is a comma separated list, empty for the defaults.
+func NewDKIM(domain, selector string, keyPEM []byte, headers string) (*DKIM, error) {
+	signer, err := parseDKIMKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	d := &DKIM{Domain: domain, Selector: selector, signer: signer}
+	for _, h := range strings.Split(headers, ",") {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			d.Headers = append(d.Headers, h)
+		}
+	}
+	if len(d.Headers) == 0 {
+		d.Headers = defaultDKIMHeaders
+	}
+	hasFrom := false
+	for _, h := range d.Headers {
+		hasFrom = hasFrom || h == "from"
+	}
+	if !hasFrom {
+		d.Headers = append([]string{"from"}, d.Headers...)
+	}
+	return d, nil
+}
+
+func parseDKIMKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	if block == nil {
+		return nil, errors.New("DKIM key is not PEM encoded")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case ed25519.PrivateKey:
+			return k, nil
+		}
+		return nil, fmt.Errorf("unsupported DKIM key type %T", key)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q for DKIM key", block.Type)
+}
+
+// Algorithm is the a= tag for the key
+func (d *DKIM) Algorithm() string {
+	if _, ok := d.signer.(ed25519.PrivateKey); ok {
+		return "ed25519-sha256"
+	}
+	return "rsa-sha256"
+}
+
+// Sign returns message with a DKIM-Signature header prepended, using
+// relaxed/relaxed canonicalisation.
+func (d *DKIM) Sign(message []byte, now time.Time) ([]byte, error) {
+	headerEnd := bytes.Index(message, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		return nil, errors.New("dkim: message has no body separator")
+	}
+	headers := splitHeaders(string(message[:headerEnd+2]))
+	body := message[headerEnd+4:]
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	// h= нь доороос дээш, тухайн нэртэй хараахан ашиглаагүй толгойг авна
+	used := map[int]bool{}
+	var names []string
+	var signed strings.Builder
+	for _, name := range d.Headers {
+		for i := len(headers) - 1; i >= 0; i-- {
+			if used[i] || headerName(headers[i]) != name {
+				continue
+			}
+			used[i] = true
+			names = append(names, name)
+			signed.WriteString(relaxedHeader(headers[i]) + "\r\n")
+			break
+		}
+	}
+
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		d.Algorithm(), d.Domain, d.Selector, now.Unix(), strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]))
+	signed.WriteString(relaxedHeader("DKIM-Signature: " + value))
+
+	digest := sha256.Sum256([]byte(signed.String()))
+	var sig []byte
+	var err error
+	if key, ok := d.signer.(ed25519.PrivateKey); ok {
+		// RFC 8463: Ed25519 нь SHA-256 хэшийг гарын үсэглэнэ
+		sig = ed25519.Sign(key, digest[:])
+	} else {
+		sig, err = d.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dkim: %w", err)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("DKIM-Signature: " + foldDKIM(value+base64.StdEncoding.EncodeToString(sig)) + "\r\n")
+	out.Write(message)
+	return out.Bytes(), nil
+}
+
+// splitHeaders returns each header with its continuation lines
+func splitHeaders(block string) []string {
+	var headers []string
+	for _, line := range strings.SplitAfter(block, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(headers) > 0 {
+			headers[len(headers)-1] += line
+		} else {
+			headers = append(headers, line)
+		}
+	}
+	for i := range headers {
+		headers[i] = strings.TrimSuffix(headers[i], "\r\n")
+	}
+	return headers
+}
+
+func headerName(header string) string {
+	name, _, _ := strings.Cut(header, ":")
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+var wsp = regexp.MustCompile(`[ \t]+`)
+
+// relaxedHeader canonicalises one header, RFC 6376 3.4.2
+func relaxedHeader(header string) string {
+	name, value, _ := strings.Cut(header, ":")
+	value = strings.NewReplacer("\r\n", "").Replace(value)
+	value = strings.TrimSpace(wsp.ReplaceAllString(value, " "))
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value
+}
+
+// relaxedBody canonicalises the body, RFC 6376 3.4.4
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(wsp.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// foldDKIM breaks the header after "; " separators and inside b=, which
+// relaxed canonicalisation and the b= rules both ignore
+func foldDKIM(value string) string {
+	tags := strings.Split(value, "; ")
+	var out strings.Builder
+	for i, tag := range tags {
+		if i > 0 {
+			out.WriteString(";\r\n\t")
+		}
+		if strings.HasPrefix(tag, "b=") {
+			for len(tag) > 72 {
+				out.WriteString(tag[:72] + "\r\n\t ")
+				tag = tag[72:]
+			}
+		}
+		out.WriteString(tag)
+	}
+	return out.String()
+}
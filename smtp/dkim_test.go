@@ -0,0 +1,341 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 8463 Appendix A: Ed25519 key and the signed example message
+const (
+	rfc8463Seed   = "nWGxne/9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A="
+	rfc8463Public = "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+	rfc8463Signed = "DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;\r\n" +
+		" d=football.example.com; i=@football.example.com;\r\n" +
+		" q=dns/txt; s=brisbane; t=1528637909; h=from : to :\r\n" +
+		" subject : date : message-id : from : subject : date;\r\n" +
+		" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+		" b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus\r\n" +
+		" Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n" +
+		rfc8463Message
+	rfc8463Message = "From: Joe SixPack <joe@football.example.com>\r\n" +
+		"To: Suzie Q <suzie@shopping.example.net>\r\n" +
+		"Subject: Is dinner ready?\r\n" +
+		"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\r\n" +
+		"Message-ID: <20030712040037.46341.5F8J@football.example.com>\r\n" +
+		"\r\n" +
+		"Hi.\r\n" +
+		"\r\n" +
+		"We lost the game.  Are you hungry yet?\r\n" +
+		"\r\n" +
+		"Joe.\r\n"
+)
+
+// emptyBodyHash is bh= of an empty body, relaxed canonicalisation adds no CRLF
+const emptyBodyHash = "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+// verifyDKIM checks the first DKIM-Signature of message the way a receiving
+// server does, with its own relaxed canonicalisation, and returns the tags
+func verifyDKIM(message []byte, public crypto.PublicKey) (map[string]string, error) {
+	raw := string(message)
+	end := strings.Index(raw, "\r\n\r\n")
+	if end < 0 {
+		return nil, errors.New("no body")
+	}
+	var fields []string
+	for _, line := range strings.Split(raw[:end], "\r\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			fields[len(fields)-1] += "\r\n" + line
+		} else {
+			fields = append(fields, line)
+		}
+	}
+	if !strings.HasPrefix(strings.ToLower(fields[0]), "dkim-signature:") {
+		return nil, errors.New("no DKIM-Signature")
+	}
+	signature := fields[0]
+	fields = fields[1:]
+
+	tags := map[string]string{}
+	_, value, _ := strings.Cut(signature, ":")
+	for _, tag := range strings.Split(value, ";") {
+		name, v, _ := strings.Cut(tag, "=")
+		tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(v), "")
+	}
+	if tags["c"] != "relaxed/relaxed" {
+		return tags, fmt.Errorf("c=%s", tags["c"])
+	}
+
+	// Их бие: мөр бүрийн WSP-г нэгтгэж, мөрийн төгсгөлийн WSP болон сүүлийн хоосон мөрүүдийг хасна
+	var body strings.Builder
+	blank := 0
+	for _, line := range strings.Split(raw[end+4:], "\r\n") {
+		line = strings.TrimRight(regexp.MustCompile(`[ \t]+`).ReplaceAllString(line, " "), " ")
+		if line == "" {
+			blank++
+			continue
+		}
+		body.WriteString(strings.Repeat("\r\n", blank) + line + "\r\n")
+		blank = 0
+	}
+	bodySum := sha256.Sum256([]byte(body.String()))
+	if bh := base64.StdEncoding.EncodeToString(bodySum[:]); bh != tags["bh"] {
+		return tags, fmt.Errorf("body hash %s, signature has %s", bh, tags["bh"])
+	}
+
+	canonical := func(field string) string {
+		name, value, _ := strings.Cut(field, ":")
+		value = strings.Join(strings.Fields(strings.ReplaceAll(value, "\r\n", "")), " ")
+		return strings.ToLower(strings.TrimSpace(name)) + ":" + value
+	}
+	var data strings.Builder
+	used := map[int]bool{}
+	for _, name := range strings.Split(tags["h"], ":") {
+		for i := len(fields) - 1; i >= 0; i-- {
+			fieldName, _, _ := strings.Cut(fields[i], ":")
+			if !used[i] && strings.EqualFold(strings.TrimSpace(fieldName), name) {
+				used[i] = true
+				data.WriteString(canonical(fields[i]) + "\r\n")
+				break
+			}
+		}
+	}
+	withoutB := regexp.MustCompile(`(^|;)(\s*b\s*=)[^;]*`).ReplaceAllString(value, "$1$2")
+	data.WriteString(canonical("DKIM-Signature:" + withoutB))
+
+	sig, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return tags, err
+	}
+	digest := sha256.Sum256([]byte(data.String()))
+	switch key := public.(type) {
+	case ed25519.PublicKey:
+		if tags["a"] != "ed25519-sha256" || !ed25519.Verify(key, digest[:], sig) {
+			return tags, errors.New("ed25519 signature does not verify")
+		}
+	case *rsa.PublicKey:
+		if tags["a"] != "rsa-sha256" {
+			return tags, fmt.Errorf("a=%s", tags["a"])
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return tags, err
+		}
+	default:
+		return tags, fmt.Errorf("unsupported key %T", public)
+	}
+	return tags, nil
+}
+
+type dkimKey struct {
+	name   string
+	pem    []byte
+	public crypto.PublicKey
+}
+
+func dkimKeys(t *testing.T) []dkimKey {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed, _ := base64.StdEncoding.DecodeString(rfc8463Seed)
+	edKey := ed25519.NewKeyFromSeed(seed)
+	pkcs8RSA, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	pkcs8Ed, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []dkimKey{
+		{"rsa pkcs1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), &rsaKey.PublicKey},
+		{"rsa pkcs8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8RSA}), &rsaKey.PublicKey},
+		{"ed25519", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Ed}), edKey.Public()},
+	}
+}
+
+func TestDKIMVerifierRFC8463(t *testing.T) {
+	public, _ := base64.StdEncoding.DecodeString(rfc8463Public)
+	if _, err := verifyDKIM([]byte(rfc8463Signed), ed25519.PublicKey(public)); err != nil {
+		t.Fatalf("RFC 8463 example does not verify: %v", err)
+	}
+	if got := base64.StdEncoding.EncodeToString(sha256Sum(relaxedBody([]byte("Hi.\r\n\r\nWe lost the game.  Are you hungry yet?\r\n\r\nJoe.\r\n")))); got != "2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=" {
+		t.Errorf("relaxedBody hash = %s", got)
+	}
+
+	d, err := NewDKIM("football.example.com", "brisbane", dkimKeys(t)[2].pem, "from,to,subject,date,message-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := d.Sign([]byte(rfc8463Message), time.Unix(1528637909, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := verifyDKIM(signed, ed25519.PublicKey(public))
+	if err != nil {
+		t.Fatalf("Sign with the RFC 8463 key: %v\n%s", err, signed)
+	}
+	if tags["bh"] != "2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=" || tags["t"] != "1528637909" || tags["h"] != "from:to:subject:date:message-id" {
+		t.Errorf("tags = %v", tags)
+	}
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func TestDKIMSign(t *testing.T) {
+	message := "From: =?UTF-8?B?0JHQsNGC?= <noreply@example.mn>\r\n" +
+		"To: bat@example.mn,\r\n" +
+		"\t dorj@example.mn\r\n" +
+		"Subject:   Чөлөөний   хүсэлт\r\n" +
+		"  #42 \t\r\n" +
+		"Date: Wed, 06 Mar 2024 10:30:00 +0800\r\n" +
+		"X-Unsigned: ignored\r\n" +
+		"\r\n" +
+		"Сайн байна уу,  \r\n" +
+		"\tхүсэлт\t \tирлээ.\t\r\n" +
+		"\r\n" +
+		"\r\n"
+	for _, key := range dkimKeys(t) {
+		t.Run(key.name, func(t *testing.T) {
+			d, err := NewDKIM("example.mn", "mail", key.pem, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := d.Sign([]byte(message), time.Unix(1709692200, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasSuffix(signed, []byte(message)) {
+				t.Fatalf("Sign changed the message:\n%s", signed)
+			}
+			tags, err := verifyDKIM(signed, key.public)
+			if err != nil {
+				t.Fatalf("verify: %v\n%s", err, signed)
+			}
+			// Байхгүй толгойнууд h=-д орохгүй
+			if tags["d"] != "example.mn" || tags["s"] != "mail" || tags["v"] != "1" || tags["h"] != "from:to:subject:date" {
+				t.Errorf("tags = %v", tags)
+			}
+			for _, line := range strings.Split(string(signed[:bytes.Index(signed, []byte("\r\n\r\n"))]), "\r\n") {
+				if len(line) > 78 {
+					t.Errorf("header line longer than 78: %q", line)
+				}
+			}
+
+			// Relaxed: дахин нугалах, хоосон зай нэмэх нь гарын үсгийг эвдэхгүй
+			refolded := strings.NewReplacer(
+				"To: bat@example.mn,\r\n\t dorj@example.mn", "To:   bat@example.mn, dorj@example.mn",
+				"ирлээ.\t\r\n", "ирлээ.\r\n",
+				"X-Unsigned: ignored", "X-Unsigned: changed",
+			).Replace(string(signed)) + "\r\n\r\n"
+			if _, err := verifyDKIM([]byte(refolded), key.public); err != nil {
+				t.Errorf("relaxed changes broke the signature: %v", err)
+			}
+			for name, tampered := range map[string]string{
+				"subject": strings.Replace(string(signed), "#42", "#43", 1),
+				"body":    strings.Replace(string(signed), "ирлээ", "ирсэн", 1),
+				"from":    strings.Replace(string(signed), "noreply@", "hr@", 1),
+				"domain":  strings.Replace(string(signed), "d=example.mn", "d=example.com", 1),
+			} {
+				if _, err := verifyDKIM([]byte(tampered), key.public); err == nil {
+					t.Errorf("tampered %s still verifies", name)
+				}
+			}
+		})
+	}
+}
+
+func TestDKIMBodyHash(t *testing.T) {
+	d, err := NewDKIM("example.mn", "mail", dkimKeys(t)[2].pem, "subject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(strings.Join(d.Headers, ","), "from,subject") {
+		t.Errorf("Headers = %v, from must always be signed", d.Headers)
+	}
+	bodyHash := func(body string) string {
+		signed, err := d.Sign([]byte("From: a@example.mn\r\nSubject: x\r\n\r\n"+body), time.Unix(0, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags, err := verifyDKIM(signed, d.signer.Public())
+		if err != nil {
+			t.Fatalf("body %q: %v", body, err)
+		}
+		return tags["bh"]
+	}
+
+	for _, body := range []string{"", "\r\n", "\r\n\r\n", " \t\r\n\r\n"} {
+		if got := bodyHash(body); got != emptyBodyHash {
+			t.Errorf("bh of %q = %s, want the empty body hash", body, got)
+		}
+	}
+	same := []string{"Hi there\r\n", "Hi there", "Hi  there \t\r\n", "Hi\tthere\r\n\r\n\r\n"}
+	for _, body := range same[1:] {
+		if got, want := bodyHash(body), bodyHash(same[0]); got != want {
+			t.Errorf("bh of %q = %s, want %s", body, got, want)
+		}
+	}
+	// Мөрийн эхний хоосон зай, дундах хоосон мөр хадгалагдана
+	for _, body := range []string{" Hi there\r\n", "Hi\r\n\r\nthere\r\n", "Hithere\r\n"} {
+		if bodyHash(body) == bodyHash(same[0]) {
+			t.Errorf("bh of %q equals bh of %q", body, same[0])
+		}
+	}
+
+	if _, err := d.Sign([]byte("From: a@example.mn\r\nSubject: x\r\n"), time.Now()); err == nil {
+		t.Error("Sign without a body separator succeeded")
+	}
+}
+
+func TestNewDKIMRejectsKeys(t *testing.T) {
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(public)
+	for name, key := range map[string][]byte{
+		"not pem":    []byte("not a key"),
+		"public key": pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		"bad pkcs8":  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("junk")}),
+	} {
+		if _, err := NewDKIM("example.mn", "mail", key, ""); err == nil {
+			t.Errorf("%s: NewDKIM succeeded", name)
+		}
+	}
+}
+
+func TestEncodeSignsWithDKIM(t *testing.T) {
+	key := dkimKeys(t)[0]
+	d, err := NewDKIM("example.mn", "mail", key.pem, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := DKIMSigner
+	DKIMSigner = d
+	t.Cleanup(func() { DKIMSigner = prev })
+
+	data, err := testMessage(1).Encode(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags, err := verifyDKIM(data, key.public)
+	if err != nil {
+		t.Fatalf("verify encoded message: %v\n%s", err, data)
+	}
+	for _, name := range []string{"from", "to", "subject", "date", "message-id", "mime-version", "content-type"} {
+		if !strings.Contains(":"+tags["h"]+":", ":"+name+":") {
+			t.Errorf("h=%s does not sign %s", tags["h"], name)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package smtp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// iTIP аргууд, RFC 5546
+const (
+	MethodRequest = "REQUEST"
+	MethodCancel  = "CANCEL"
+)
+
+// Event нь имэйлд хавсаргах хуанлийн VEVENT. UID өөрчлөгдөхгүй тул CANCEL
+// нь өмнөх REQUEST-ийг хуанлиас устгана.
+type Event struct {
+	UID         string    `json:"uid"`
+	Method      string    `json:"method"`   // REQUEST, CANCEL
+	Sequence    int       `json:"sequence"` // Өөрчлөлт бүрт нэмэгдэнэ
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	AllDay      bool      `json:"all_day"` // Огноогоор, End өдөр орно
+	Summary     string    `json:"summary"`
+	Description string    `json:"description,omitempty"`
+	Organizer   string    `json:"organizer"`
+	Attendees   []string  `json:"attendees,omitempty"`
+}
+
+// ICS renders the event as an iCalendar object with CRLF lines folded at 75 octets
+func (e *Event) ICS(now time.Time) []byte {
+	method := e.Method
+	if method == "" {
+		method = MethodRequest
+	}
+	status := "CONFIRMED"
+	if method == MethodCancel {
+		status = "CANCELLED"
+	}
+
+	var buf bytes.Buffer
+	line := func(s string) { writeICSLine(&buf, s) }
+	line("BEGIN:VCALENDAR")
+	line("PRODID:-//FIBO GLOBAL//MCP Absence//EN")
+	line("VERSION:2.0")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:" + method)
+	line("BEGIN:VEVENT")
+	line("UID:" + e.UID)
+	line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	line("DTSTAMP:" + icsTime(now))
+	if e.AllDay {
+		line("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+		end := time.Date(e.End.Year(), e.End.Month(), e.End.Day(), 0, 0, 0, 0, e.End.Location()).AddDate(0, 0, 1)
+		line("DTEND;VALUE=DATE:" + end.Format("20060102"))
+	} else {
+		line("DTSTART:" + icsTime(e.Start))
+		line("DTEND:" + icsTime(e.End))
+	}
+	line("SUMMARY:" + icsEscape(e.Summary))
+	if e.Description != "" {
+		line("DESCRIPTION:" + icsEscape(e.Description))
+	}
+	if e.Organizer != "" {
+		line("ORGANIZER:mailto:" + e.Organizer)
+	}
+	for _, a := range e.Attendees {
+		line("ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED:mailto:" + a)
+	}
+	line("STATUS:" + status)
+	line("TRANSP:OPAQUE")
+	line("X-MICROSOFT-CDO-BUSYSTATUS:OOF")
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// ContentType is the MIME type of the invite, method is required by Outlook
+func (e *Event) ContentType() string {
+	method := e.Method
+	if method == "" {
+		method = MethodRequest
+	}
+	return "text/calendar; method=" + method
+}
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine folds s at 75 octets without splitting a UTF-8 character
+func writeICSLine(buf *bytes.Buffer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		buf.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74 // Дараагийн мөр зайгаар эхэлнэ
+	}
+	buf.WriteString(s + "\r\n")
+}
@@ -0,0 +1,113 @@
+package smtp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Mailer бэлэн болсон имэйлийг хүргэнэ
+type Mailer interface {
+	Deliver(msg *Message) error
+}
+
+// Default нь MAIL_DRIVER-ээр сонгогдсон mailer, Configure тохируулна
+var Default Mailer
+
+// Configure selects the mailer from MAIL_DRIVER: smtp (default), file or memory
+func Configure() (Mailer, error) {
+	switch driver := viper.GetString("MAIL_DRIVER"); driver {
+	case "", "smtp":
+		client := CreateClient()
+		if err := client.Validate(); err != nil {
+			return nil, err
+		}
+		Default = client
+	case "file":
+		dir := viper.GetString("MAIL_DROP_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		Default = &FileDrop{Dir: dir}
+	case "memory":
+		Default = &Recorder{}
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+	fmt.Printf("Mail driver: %T\n", Default)
+	return Default, nil
+}
+
+// Compose renders the template into a message from SMTP_FROM
+func Compose(input EmailInput, param map[string]interface{}) (*Message, error) {
+	return Render(viper.GetString("SMTP_FROM"), input, param)
+}
+
+// Send renders the template and delivers it with the default mailer
+func Send(input EmailInput, param map[string]interface{}) error {
+	if Default == nil {
+		return fmt.Errorf("mailer is not configured")
+	}
+	msg, err := Compose(input, param)
+	if err != nil {
+		return err
+	}
+	return Default.Deliver(msg)
+}
+
+// FileDrop нь имэйлийг .eml файл болгон хавтсанд бичнэ, локал хөгжүүлэлтэд
+type FileDrop struct {
+	Dir string
+}
+
+func (f *FileDrop) Deliver(msg *Message) error {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000"), hex.EncodeToString(suffix))
+	path := filepath.Join(f.Dir, name)
+	data, err := msg.Encode(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Println("Mail written to", path)
+	return nil
+}
+
+// Recorder нь имэйлийг санах ойд хадгална, тест болон шалгалтад
+type Recorder struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func (r *Recorder) Deliver(msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of the recorded messages
+func (r *Recorder) Messages() []*Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Message(nil), r.messages...)
+}
+
+// Reset forgets the recorded messages
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = nil
+}
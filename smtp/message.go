@@ -0,0 +1,109 @@
+package smtp
+
+import (
+	"fmt"
+	"net/mail"
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// fromName is the display name of the sender, MAIL_FROM_NAME
+func fromName() string {
+	if name := viper.GetString("MAIL_FROM_NAME"); name != "" {
+		return name
+	}
+	return "FIBO GLOBAL"
+}
+
+// Message илгээхэд бэлэн болсон имэйл, тээвэрлэгчээс хамааралгүй
+type Message struct {
+	MessageID   string            `json:"message_id,omitempty"` // Хоосон бол Build үүсгэнэ
+	From        string            `json:"from"`
+	FromName    string            `json:"from_name,omitempty"`
+	To          []string          `json:"to"`
+	Cc          []string          `json:"cc,omitempty"`
+	Bcc         []string          `json:"bcc,omitempty"` // Зөвхөн envelope-д, толгойд бичихгүй
+	Subject     string            `json:"subject"`
+	HTML        string            `json:"html"`
+	Text        string            `json:"text,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"` // Нэмэлт толгойнууд
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Calendar    *Event            `json:"calendar,omitempty"` // Хуанлийн урилга, REQUEST эсвэл CANCEL
+}
+
+// Recipients returns every envelope address, To, Cc then Bcc
+func (m *Message) Recipients() []string {
+	var rcpt []string
+	all := append(append(append([]string{}, m.To...), m.Cc...), m.Bcc...)
+	for _, raw := range all {
+		if raw == "" {
+			continue
+		}
+		if addr, err := mail.ParseAddress(raw); err == nil {
+			rcpt = append(rcpt, addr.Address)
+		} else {
+			rcpt = append(rcpt, raw)
+		}
+	}
+	return rcpt
+}
+
+// Encode builds the message and signs it when DKIM is configured
+func (m *Message) Encode(now time.Time) ([]byte, error) {
+	data, err := m.Build(now)
+	if err != nil || DKIMSigner == nil {
+		return data, err
+	}
+	return DKIMSigner.Sign(data, now)
+}
+
+// Bytes renders the message as it is written to the DATA command
+func (m *Message) Bytes() []byte {
+	data, err := m.Encode(time.Now())
+	if err != nil {
+		fmt.Println("Failed to build message", err)
+	}
+	return data
+}
+
+func sortedKeys(headers map[string]string) []string {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Render executes the input's template from the registry into a message
+// from the given sender. EmailInput.Subtitle is available as .subtitle.
+func Render(from string, input EmailInput, param map[string]interface{}) (*Message, error) {
+	data := map[string]interface{}{}
+	for k, v := range param {
+		data[k] = v
+	}
+	if input.Subtitle != "" {
+		data["subtitle"] = input.Subtitle
+	}
+	rendered, err := Templates.Render(input.Template, input.Language, data)
+	if err != nil {
+		fmt.Println("Template error", err.Error())
+		return nil, err
+	}
+	if len(rendered.Missing) > 0 {
+		fmt.Println("Template", input.Template, "rendered without", rendered.Missing)
+	}
+	return &Message{
+		From:     from,
+		FromName: fromName(),
+		To:       []string{input.Email},
+		Cc:       input.Cc,
+		Bcc:      input.MultiBcc,
+		Subject:  rendered.Subject,
+		HTML:     rendered.HTML,
+		Text:     rendered.Text,
+		Calendar: input.Calendar,
+	}, nil
+}
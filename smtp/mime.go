@@ -0,0 +1,256 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const maxHeaderLine = 78
+
+// Attachment нь хавсралт файл эсвэл HTML дотор cid:-ээр харуулах зураг
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+	ContentID   string `json:"content_id,omitempty"` // Байвал inline, HTML-д <img src="cid:...">
+}
+
+// Inline reports whether the attachment is shown inside the HTML body
+func (a Attachment) Inline() bool {
+	return a.ContentID != ""
+}
+
+// mimePart is one node of the MIME tree; multipart nodes have children
+type mimePart struct {
+	header   textproto.MIMEHeader
+	body     []byte
+	subtype  string
+	children []*mimePart
+}
+
+func multipartNode(subtype string, children ...*mimePart) *mimePart {
+	if len(children) == 1 {
+		return children[0]
+	}
+	return &mimePart{subtype: subtype, children: children}
+}
+
+// textNode encodes content as quoted-printable, contentType gets the charset
+func textNode(contentType, content string) *mimePart {
+	var buf bytes.Buffer
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(content))
+	qp.Close()
+	return &mimePart{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		body: buf.Bytes(),
+	}
+}
+
+func attachmentNode(a Attachment) *mimePart {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": a.Filename})},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	disposition := "attachment"
+	if a.Inline() {
+		disposition = "inline"
+		header.Set("Content-ID", "<"+a.ContentID+">")
+	}
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	return &mimePart{header: header, body: wrapBase64(a.Data)}
+}
+
+// wrapBase64 encodes data in lines of 76 characters
+func wrapBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
+
+// headers returns the Content-Type of a multipart node with a fresh boundary
+func (p *mimePart) prepare() string {
+	if p.subtype == "" {
+		return ""
+	}
+	boundary := randomHex(15)
+	p.header = textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType("multipart/"+p.subtype, map[string]string{"boundary": boundary})},
+	}
+	return boundary
+}
+
+// writeBody writes the body of p; multipart children are written recursively
+func (p *mimePart) writeBody(w io.Writer, boundary string) error {
+	if p.subtype == "" {
+		_, err := w.Write(p.body)
+		return err
+	}
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, child := range p.children {
+		childBoundary := child.prepare()
+		part, err := mw.CreatePart(child.header)
+		if err != nil {
+			return err
+		}
+		if err := child.writeBody(part, childBoundary); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// tree arranges the body as
+// mixed(related(alternative(text, html, calendar), inline...), invite.ics, attachments...)
+func (m *Message) tree() *mimePart {
+	var alternatives []*mimePart
+	if m.Text != "" {
+		alternatives = append(alternatives, textNode("text/plain", m.Text))
+	}
+	if m.HTML != "" || m.Text == "" {
+		alternatives = append(alternatives, textNode("text/html", m.HTML))
+	}
+	mixed := []*mimePart{}
+	if m.Calendar != nil {
+		// Хуанли программууд alternative доторх урилгыг шууд уншина,
+		// бусад нь .ics хавсралтыг нээнэ
+		ics := string(m.Calendar.ICS(time.Now()))
+		alternatives = append(alternatives, textNode(m.Calendar.ContentType(), ics))
+		mixed = append(mixed, attachmentNode(Attachment{
+			Filename:    "invite.ics",
+			ContentType: "application/ics",
+			Data:        []byte(ics),
+		}))
+	}
+	body := multipartNode("alternative", alternatives...)
+
+	related := []*mimePart{body}
+	for _, a := range m.Attachments {
+		if a.Inline() {
+			related = append(related, attachmentNode(a))
+		} else {
+			mixed = append(mixed, attachmentNode(a))
+		}
+	}
+	body = multipartNode("related", related...)
+	return multipartNode("mixed", append([]*mimePart{body}, mixed...)...)
+}
+
+// formatAddress encodes the display name of "Name <addr>" per RFC 2047
+func formatAddress(name, address string) string {
+	return (&mail.Address{Name: name, Address: address}).String()
+}
+
+// formatAddressList re-encodes each address of a list, keeping unparsable ones as given
+func formatAddressList(list []string) string {
+	var out []string
+	for _, raw := range list {
+		if raw == "" {
+			continue
+		}
+		if addr, err := mail.ParseAddress(raw); err == nil {
+			out = append(out, addr.String())
+		} else {
+			out = append(out, raw)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// writeHeader writes "Name: value" folded at spaces to 78 characters. A long
+// first word, e.g. an encoded-word, goes to a continuation line of its own.
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	line := name + ":"
+	for _, word := range strings.Split(value, " ") {
+		if len(line)+1+len(word) > maxHeaderLine && line != "" {
+			buf.WriteString(line + "\r\n")
+			line = ""
+		}
+		line += " " + word
+	}
+	buf.WriteString(line + "\r\n")
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newMessageID returns a Message-ID on the sender's domain
+func newMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = strings.TrimSuffix(from[at+1:], ">")
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), randomHex(8), domain)
+}
+
+// EnsureMessageID assigns a Message-ID once so retries of the same message
+// keep it and receivers can drop duplicates
+func (m *Message) EnsureMessageID() string {
+	if m.MessageID == "" {
+		m.MessageID = newMessageID(m.From)
+	}
+	return m.MessageID
+}
+
+// Build renders the message for the DATA command. Headers are written in a
+// fixed order, non-ASCII subjects and display names are RFC 2047 encoded and
+// Bcc recipients only appear in the envelope.
+func (m *Message) Build(now time.Time) ([]byte, error) {
+	m.EnsureMessageID()
+	root := m.tree()
+	boundary := root.prepare()
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "Date", now.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", m.MessageID)
+	writeHeader(&buf, "From", formatAddress(m.FromName, m.From))
+	writeHeader(&buf, "To", formatAddressList(m.To))
+	if cc := formatAddressList(m.Cc); cc != "" {
+		writeHeader(&buf, "Cc", cc)
+	}
+	writeHeader(&buf, "Reply-To", formatAddress("", m.From))
+	writeHeader(&buf, "Subject", mime.BEncoding.Encode("UTF-8", m.Subject))
+	for _, name := range sortedKeys(m.Headers) {
+		writeHeader(&buf, name, mime.QEncoding.Encode("UTF-8", m.Headers[name]))
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+	for _, name := range []string{"Content-Type", "Content-Transfer-Encoding", "Content-Disposition", "Content-ID"} {
+		if value := root.header.Get(name); value != "" {
+			writeHeader(&buf, name, value)
+		}
+	}
+	buf.WriteString("\r\n")
+	if err := root.writeBody(&buf, boundary); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
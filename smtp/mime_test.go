@@ -0,0 +1,315 @@
+package smtp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var buildTime = time.Date(2024, 3, 5, 9, 30, 0, 0, time.FixedZone("ULAT", 8*3600))
+
+// headerNames returns the header field names of raw in the order written
+func headerNames(t *testing.T, raw []byte) []string {
+	t.Helper()
+	head, _, ok := bytes.Cut(raw, []byte("\r\n\r\n"))
+	if !ok {
+		t.Fatal("message has no header/body separator")
+	}
+	var names []string
+	for _, line := range strings.Split(string(head), "\r\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		name, _, _ := strings.Cut(line, ":")
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestBuildHeaderOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want []string
+	}{
+		{
+			name: "text only",
+			msg:  Message{From: "noreply@example.mn", To: []string{"a@example.mn"}, Subject: "Hi", Text: "hello"},
+			want: []string{"Date", "Message-ID", "From", "To", "Reply-To", "Subject", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"},
+		},
+		{
+			name: "cc and extra headers",
+			msg: Message{
+				From: "noreply@example.mn", To: []string{"a@example.mn"}, Cc: []string{"b@example.mn"},
+				Subject: "Hi", HTML: "<p>hello</p>", Text: "hello",
+				Headers: map[string]string{"X-Template": "absence_request", "Auto-Submitted": "auto-generated"},
+			},
+			want: []string{"Date", "Message-ID", "From", "To", "Cc", "Reply-To", "Subject", "Auto-Submitted", "X-Template", "MIME-Version", "Content-Type"},
+		},
+	}
+	for _, tt := range tests {
+		raw, err := tt.msg.Build(buildTime)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := headerNames(t, raw); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: headers\n got %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildOmitsBcc(t *testing.T) {
+	msg := Message{
+		From: "noreply@example.mn", To: []string{"a@example.mn"}, Bcc: []string{"hidden@example.mn"},
+		Subject: "Hi", Text: "hello",
+	}
+	raw, err := msg.Build(buildTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(bytes.ToLower(raw), []byte("bcc:")) || bytes.Contains(raw, []byte("hidden@example.mn")) {
+		t.Errorf("Bcc leaked into the message:\n%s", raw)
+	}
+	if rcpt := msg.Recipients(); len(rcpt) != 2 || rcpt[1] != "hidden@example.mn" {
+		t.Errorf("Recipients() = %v, want the Bcc address in the envelope", rcpt)
+	}
+}
+
+func TestBuildEncodesNonASCII(t *testing.T) {
+	tests := []struct {
+		subject, fromName string
+	}{
+		{"Чөлөөний хүсэлт", "Хүний нөөц"},
+		// Урт гарчиг олон encoded-word болж мөрөөр хуваагдана
+		{"Таны чөлөөний хүсэлт #42 зөвшөөрөгдлөө, дэлгэрэнгүйг доороос харна уу", "ФИБО ГЛОБАЛ ХХК Хүний нөөцийн алба"},
+		{"Plain subject", "FIBO GLOBAL"},
+	}
+	dec := new(mime.WordDecoder)
+	for _, tt := range tests {
+		msg := Message{From: "noreply@example.mn", FromName: tt.fromName, To: []string{"Бат <bat@example.mn>"}, Subject: tt.subject, Text: "hello"}
+		raw, err := msg.Build(buildTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, _, _ := bytes.Cut(raw, []byte("\r\n\r\n"))
+		for _, line := range strings.Split(string(head), "\r\n") {
+			if len(line) > maxHeaderLine {
+				t.Errorf("header line longer than %d: %q", maxHeaderLine, line)
+			}
+			for _, r := range line {
+				if r > 127 {
+					t.Errorf("raw non-ASCII in header: %q", line)
+					break
+				}
+			}
+		}
+
+		parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subject, err := dec.DecodeHeader(parsed.Header.Get("Subject"))
+		if err != nil || subject != tt.subject {
+			t.Errorf("Subject = %q (%v), want %q", subject, err, tt.subject)
+		}
+		from, err := mail.ParseAddress(parsed.Header.Get("From"))
+		if err != nil || from.Name != tt.fromName || from.Address != "noreply@example.mn" {
+			t.Errorf("From = %+v (%v), want %q <noreply@example.mn>", from, err, tt.fromName)
+		}
+		to, err := mail.ParseAddress(parsed.Header.Get("To"))
+		if err != nil || to.Name != "Бат" {
+			t.Errorf("To = %+v (%v)", to, err)
+		}
+	}
+}
+
+func TestBuildDateAndMessageID(t *testing.T) {
+	msg := Message{From: "FIBO <noreply@example.mn>", To: []string{"a@example.mn"}, Subject: "Hi", Text: "hello"}
+	raw, err := msg.Build(buildTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	date, err := mail.ParseDate(parsed.Header.Get("Date"))
+	if err != nil || !date.Equal(buildTime) {
+		t.Errorf("Date = %q (%v), want %s", parsed.Header.Get("Date"), err, buildTime.Format(time.RFC1123Z))
+	}
+	id := parsed.Header.Get("Message-ID")
+	if !regexp.MustCompile(`^<\d+\.[0-9a-f]{16}@example\.mn>$`).MatchString(id) {
+		t.Errorf("Message-ID = %q is not <unique@domain>", id)
+	}
+
+	// Дахин оролдоход ижил Message-ID
+	again, err := msg.Build(buildTime.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(again, []byte("Message-ID: "+id+"\r\n")) {
+		t.Errorf("Message-ID changed between builds")
+	}
+}
+
+// mimeTree describes the part structure, e.g. "mixed(alternative(text/plain,text/html),application/pdf)",
+// and checks each multipart uses the boundary from its own Content-Type
+func mimeTree(t *testing.T, header textproto.MIMEHeader, body io.Reader, boundaries map[string]bool, leaves *[]*leafPart) string {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Content-Type %q: %v", header.Get("Content-Type"), err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*leaves = append(*leaves, &leafPart{header: header, body: data})
+		return mediaType
+	}
+	boundary := params["boundary"]
+	if boundary == "" || boundaries[boundary] {
+		t.Fatalf("%s has an empty or reused boundary %q", mediaType, boundary)
+	}
+	boundaries[boundary] = true
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("\r\n--"+boundary+"--")) {
+		t.Errorf("%s is not closed with its boundary", mediaType)
+	}
+	reader := multipart.NewReader(bytes.NewReader(data), boundary)
+	var children []string
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", mediaType, err)
+		}
+		children = append(children, mimeTree(t, part.Header, part, boundaries, leaves))
+	}
+	return strings.TrimPrefix(mediaType, "multipart/") + "(" + strings.Join(children, ",") + ")"
+}
+
+type leafPart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func TestBuildMultipart(t *testing.T) {
+	logo := bytes.Repeat([]byte{0x89, 'P', 'N', 'G', 0x00, 0xff}, 50)
+	pdf := bytes.Repeat([]byte("%PDF-1.4 тест "), 40)
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{
+			name: "text only",
+			msg:  Message{Text: "hello"},
+			want: "text/plain",
+		},
+		{
+			name: "alternative",
+			msg:  Message{Text: "hello", HTML: "<p>hello</p>"},
+			want: "alternative(text/plain,text/html)",
+		},
+		{
+			name: "attachment",
+			msg: Message{Text: "hello", HTML: "<p>hello</p>", Attachments: []Attachment{
+				{Filename: "тодорхойлолт.pdf", ContentType: "application/pdf", Data: pdf},
+			}},
+			want: "mixed(alternative(text/plain,text/html),application/pdf)",
+		},
+		{
+			name: "inline and attachment",
+			msg: Message{Text: "hello", HTML: `<p>hello <img src="cid:logo@example.mn"></p>`, Attachments: []Attachment{
+				{Filename: "logo.png", ContentType: "image/png", Data: logo, ContentID: "logo@example.mn"},
+				{Filename: "тодорхойлолт.pdf", ContentType: "application/pdf", Data: pdf},
+			}},
+			want: "mixed(related(alternative(text/plain,text/html),image/png),application/pdf)",
+		},
+	}
+	for _, tt := range tests {
+		tt.msg.From, tt.msg.To, tt.msg.Subject = "noreply@example.mn", []string{"a@example.mn"}, "Хавсралт"
+		raw, err := tt.msg.Build(buildTime)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var leaves []*leafPart
+		got := mimeTree(t, textproto.MIMEHeader(parsed.Header), parsed.Body, map[string]bool{}, &leaves)
+		if got != tt.want {
+			t.Errorf("%s: tree = %s, want %s", tt.name, got, tt.want)
+		}
+
+		for _, leaf := range leaves {
+			mediaType, params, _ := mime.ParseMediaType(leaf.header.Get("Content-Type"))
+			switch mediaType {
+			case "text/plain", "text/html":
+				if leaf.header.Get("Content-Transfer-Encoding") != "quoted-printable" || !strings.EqualFold(params["charset"], "UTF-8") {
+					t.Errorf("%s: %s headers %v", tt.name, mediaType, leaf.header)
+				}
+			case "image/png", "application/pdf":
+				checkBase64Part(t, tt.name, leaf, map[string][]byte{"image/png": logo, "application/pdf": pdf}[mediaType])
+			}
+		}
+	}
+}
+
+func checkBase64Part(t *testing.T, name string, leaf *leafPart, want []byte) {
+	t.Helper()
+	if leaf.header.Get("Content-Transfer-Encoding") != "base64" {
+		t.Errorf("%s: Content-Transfer-Encoding = %q", name, leaf.header.Get("Content-Transfer-Encoding"))
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(leaf.body))
+	var encoded strings.Builder
+	lines := 0
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) > 76 {
+			t.Errorf("%s: base64 line of %d characters", name, len(line))
+		}
+		encoded.WriteString(line)
+		lines++
+	}
+	if lines < 2 {
+		t.Errorf("%s: expected the base64 body to be wrapped, got %d line", name, lines)
+	}
+	if !bytes.Contains(leaf.body, []byte("\r\n")) || bytes.Contains(bytes.ReplaceAll(leaf.body, []byte("\r\n"), nil), []byte("\n")) {
+		t.Errorf("%s: base64 lines must end with CRLF", name)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil || !bytes.Equal(data, want) {
+		t.Errorf("%s: base64 body does not decode to the attachment (%v)", name, err)
+	}
+
+	disposition, params, err := mime.ParseMediaType(leaf.header.Get("Content-Disposition"))
+	if err != nil {
+		t.Fatalf("%s: Content-Disposition: %v", name, err)
+	}
+	if cid := leaf.header.Get("Content-ID"); cid != "" {
+		if disposition != "inline" || cid != "<logo@example.mn>" {
+			t.Errorf("%s: inline part has disposition %q and Content-ID %q", name, disposition, cid)
+		}
+	} else if disposition != "attachment" || params["filename"] != "тодорхойлолт.pdf" {
+		t.Errorf("%s: attachment has disposition %q and filename %q", name, disposition, params["filename"])
+	}
+}
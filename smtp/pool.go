@@ -0,0 +1,126 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// conn нь нэвтэрсэн SMTP холболт, хэд хэдэн мессежид дахин ашиглагдана
+type conn struct {
+	raw      net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// send runs one MAIL/RCPT/DATA transaction within timeout
+func (cn *conn) send(msg *Message, timeout time.Duration) error {
+	data, err := msg.Encode(time.Now())
+	if err != nil {
+		return err
+	}
+	cn.raw.SetDeadline(time.Now().Add(timeout))
+
+	if err := cn.client.Mail(msg.From); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err := cn.client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("rcpt %s: %w", rcpt, err)
+		}
+	}
+	w, err := cn.client.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err = w.Write(data); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("data close: %w", err)
+	}
+	return nil
+}
+
+func (cn *conn) close() {
+	cn.raw.SetDeadline(time.Now().Add(time.Second))
+	cn.client.Quit()
+	cn.client.Close()
+}
+
+func (c *Client) idleTimeout() time.Duration {
+	if c.IdleTimeout > 0 {
+		return c.IdleTimeout
+	}
+	return defaultIdleTimeout
+}
+
+// acquire returns an idle connection that still answers NOOP or dials a new one
+func (c *Client) acquire() (*conn, error) {
+	for {
+		c.mu.Lock()
+		if len(c.idle) == 0 {
+			c.mu.Unlock()
+			return c.dial()
+		}
+		cn := c.idle[len(c.idle)-1]
+		c.idle = c.idle[:len(c.idle)-1]
+		c.mu.Unlock()
+
+		if time.Since(cn.lastUsed) > c.idleTimeout() {
+			cn.close()
+			continue
+		}
+		cn.raw.SetDeadline(time.Now().Add(c.timeout()))
+		if err := cn.client.Noop(); err != nil {
+			cn.client.Close()
+			continue
+		}
+		return cn, nil
+	}
+}
+
+// release keeps cn for the next message, e.g. the rest of an outbox batch
+func (c *Client) release(cn *conn) {
+	cn.lastUsed = time.Now()
+	c.mu.Lock()
+	if len(c.idle) >= c.PoolSize {
+		c.mu.Unlock()
+		cn.close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+	c.mu.Unlock()
+	time.AfterFunc(c.idleTimeout(), c.closeIdle)
+}
+
+// closeIdle closes connections unused for longer than the idle timeout
+func (c *Client) closeIdle() {
+	c.mu.Lock()
+	var stale, keep []*conn
+	for _, cn := range c.idle {
+		if time.Since(cn.lastUsed) >= c.idleTimeout() {
+			stale = append(stale, cn)
+		} else {
+			keep = append(keep, cn)
+		}
+	}
+	c.idle = keep
+	c.mu.Unlock()
+	for _, cn := range stale {
+		cn.close()
+	}
+}
+
+// Close closes every idle connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = nil
+	c.mu.Unlock()
+	for _, cn := range idle {
+		cn.close()
+	}
+	return nil
+}
@@ -2,7 +2,10 @@ package smtp
 
 type EmailInput struct {
 	Email    string   `json:"email"`
+	Cc       []string `json:"cc"`
 	MultiBcc []string `json:"multi_bcc"`
 	Subtitle string   `json:"subtitle"`
 	Template string   `json:"template"`
+	Language string   `json:"language"` // mn, en; хоосон бол MAIL_DEFAULT_LANGUAGE
+	Calendar *Event   `json:"calendar"` // Хавсаргах хуанлийн урилга
 }
@@ -0,0 +1,403 @@
+package smtp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"mcp-server/files"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"github.com/spf13/viper"
+)
+
+// Загварын хэсгүүд: <name>.<lang>.subject, <name>.<lang>.html, <name>.<lang>.txt
+const (
+	partSubject = "subject"
+	partHTML    = "html"
+	partText    = "txt"
+)
+
+const defaultLanguage = "mn"
+
+var ErrTemplateNotFound = errors.New("email template not found")
+
+// Rendered нь загварыг өгөгдлөөр гүйцээсэн үр дүн
+type Rendered struct {
+	Template string   `json:"template"`
+	Language string   `json:"language"`
+	Subject  string   `json:"subject"`
+	HTML     string   `json:"html"`
+	Text     string   `json:"text"`
+	Missing  []string `json:"missing_variables,omitempty"` // Өгөгдөлд байхгүй хувьсагчууд
+}
+
+// Registry нь суулгасан загваруудыг, override хавтас байвал түүнийг нь түрүүлж уншина
+type Registry struct {
+	embedded fs.FS
+	override string
+	language string
+
+	mu    sync.Mutex
+	cache map[string]interface{} // embedded загварын parse хийсэн хувилбар
+}
+
+// Templates is the registry used by Compose
+var Templates = NewRegistry(mustSub(files.EmailTemplates, "email-template"), "", defaultLanguage)
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+func NewRegistry(embedded fs.FS, override, language string) *Registry {
+	if language == "" {
+		language = defaultLanguage
+	}
+	return &Registry{embedded: embedded, override: override, language: language, cache: map[string]interface{}{}}
+}
+
+// ConfigureTemplates applies MAIL_TEMPLATE_DIR (files there win over the
+// embedded ones, so designers can edit without a rebuild) and
+// MAIL_DEFAULT_LANGUAGE
+func ConfigureTemplates() error {
+	dir := viper.GetString("MAIL_TEMPLATE_DIR")
+	if dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("MAIL_TEMPLATE_DIR %q is not a directory", dir)
+		}
+	}
+	Templates = NewRegistry(Templates.embedded, dir, viper.GetString("MAIL_DEFAULT_LANGUAGE"))
+	return nil
+}
+
+// DefaultLanguage returns the language used when the recipient has none
+func (r *Registry) DefaultLanguage() string {
+	return r.language
+}
+
+// Names returns the registered template names with their languages
+func (r *Registry) Names() map[string][]string {
+	found := map[string]map[string]bool{}
+	collect := func(fsys fs.FS) {
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			parts := strings.Split(e.Name(), ".")
+			if e.IsDir() || len(parts) != 3 || parts[2] != partHTML {
+				continue
+			}
+			if found[parts[0]] == nil {
+				found[parts[0]] = map[string]bool{}
+			}
+			found[parts[0]][parts[1]] = true
+		}
+	}
+	collect(r.embedded)
+	if r.override != "" {
+		collect(os.DirFS(r.override))
+	}
+
+	names := map[string][]string{}
+	for name, langs := range found {
+		for lang := range langs {
+			names[name] = append(names[name], lang)
+		}
+		sort.Strings(names[name])
+	}
+	return names
+}
+
+// read returns the file of a template part, preferring the override directory
+func (r *Registry) read(file string) ([]byte, bool, error) {
+	if r.override != "" {
+		data, err := os.ReadFile(r.override + string(os.PathSeparator) + file)
+		if err == nil {
+			return data, false, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, false, err
+		}
+	}
+	data, err := fs.ReadFile(r.embedded, file)
+	return data, true, err
+}
+
+// resolve finds the language to use for name: the requested one, then the default
+func (r *Registry) resolve(name, lang string) (string, error) {
+	for _, candidate := range []string{lang, r.language} {
+		if candidate == "" {
+			continue
+		}
+		if _, _, err := r.read(name + "." + candidate + "." + partHTML); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
+// parse returns the parsed template of one part, nil when the part does not exist
+func (r *Registry) parse(file string, html bool) (interface{}, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[file]
+	r.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	data, embedded, err := r.read(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	if html {
+		parsed, err = htmltemplate.New(file).Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(string(data))
+	} else {
+		parsed, err = texttemplate.New(file).Funcs(texttemplate.FuncMap(templateFuncs)).Parse(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	if embedded {
+		r.mu.Lock()
+		r.cache[file] = parsed
+		r.mu.Unlock()
+	}
+	return parsed, nil
+}
+
+func (r *Registry) execute(file string, html bool, data map[string]interface{}) (string, bool, error) {
+	parsed, err := r.parse(file, html)
+	if err != nil || parsed == nil {
+		return "", false, err
+	}
+	var out bytes.Buffer
+	switch t := parsed.(type) {
+	case *htmltemplate.Template:
+		err = t.Execute(&out, data)
+	case *texttemplate.Template:
+		err = t.Execute(&out, data)
+	}
+	if err != nil {
+		return "", true, fmt.Errorf("execute %s: %w", file, err)
+	}
+	return out.String(), true, nil
+}
+
+// Render executes the subject, HTML and text parts of name in lang, falling
+// back to the default language when the template has no such variant.
+// Variables missing from data (or nil) render empty instead of "<no value>"
+// and are listed in Rendered.Missing.
+func (r *Registry) Render(name, lang string, data map[string]interface{}) (*Rendered, error) {
+	return r.render(name, lang, data, func(string) interface{} { return "" })
+}
+
+// Preview renders like Render but shows missing variables as [[name]]
+func (r *Registry) Preview(name, lang string, data map[string]interface{}) (*Rendered, error) {
+	return r.render(name, lang, data, func(field string) interface{} { return "[[" + field + "]]" })
+}
+
+func (r *Registry) render(name, lang string, data map[string]interface{}, fill func(string) interface{}) (*Rendered, error) {
+	lang, err := r.resolve(name, lang)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := r.Variables(name, lang)
+	if err != nil {
+		return nil, err
+	}
+	filled := map[string]interface{}{}
+	for k, v := range data {
+		filled[k] = v
+	}
+	result := &Rendered{Template: name, Language: lang}
+	for _, field := range vars.Required {
+		if v, ok := data[field]; !ok || v == nil {
+			result.Missing = append(result.Missing, field)
+			filled[field] = fill(field)
+		}
+	}
+
+	base := name + "." + lang + "."
+	if result.HTML, _, err = r.execute(base+partHTML, true, filled); err != nil {
+		return nil, err
+	}
+	if result.Text, _, err = r.execute(base+partText, false, filled); err != nil {
+		return nil, err
+	}
+	subject, ok, err := r.execute(base+partSubject, false, filled)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		subject = name
+	}
+	// Гарчиг нэг мөр байх ёстой
+	result.Subject = strings.Join(strings.Fields(subject), " ")
+	return result, nil
+}
+
+// Sample returns the example data of name from <name>.sample.json, if any
+func (r *Registry) Sample(name string) (map[string]interface{}, error) {
+	data, _, err := r.read(name + ".sample.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sample := map[string]interface{}{}
+	if err := json.Unmarshal(data, &sample); err != nil {
+		return nil, fmt.Errorf("parse %s.sample.json: %w", name, err)
+	}
+	return sample, nil
+}
+
+// Variables lists the top-level fields a template reads. Required ones are
+// printed unconditionally, optional ones only appear in if/with/range
+// conditions or under a guard on the same field.
+type Variables struct {
+	Required []string `json:"required"`
+	Optional []string `json:"optional"`
+}
+
+type fieldSet struct {
+	required map[string]bool
+	optional map[string]bool
+}
+
+// Variables collects the variables of every part of name in lang
+func (r *Registry) Variables(name, lang string) (Variables, error) {
+	lang, err := r.resolve(name, lang)
+	if err != nil {
+		return Variables{}, err
+	}
+	set := fieldSet{required: map[string]bool{}, optional: map[string]bool{}}
+	for _, part := range []struct {
+		ext  string
+		html bool
+	}{{partSubject, false}, {partHTML, true}, {partText, false}} {
+		parsed, err := r.parse(name+"."+lang+"."+part.ext, part.html)
+		if err != nil {
+			return Variables{}, err
+		}
+		var tree *parse.Tree
+		switch t := parsed.(type) {
+		case *htmltemplate.Template:
+			tree = t.Tree
+		case *texttemplate.Template:
+			tree = t.Tree
+		}
+		if tree != nil && tree.Root != nil {
+			set.walk(tree.Root, map[string]bool{}, true)
+		}
+	}
+
+	var vars Variables
+	for field := range set.required {
+		vars.Required = append(vars.Required, field)
+	}
+	for field := range set.optional {
+		if !set.required[field] {
+			vars.Optional = append(vars.Optional, field)
+		}
+	}
+	sort.Strings(vars.Required)
+	sort.Strings(vars.Optional)
+	return vars, nil
+}
+
+// walk records fields used under node. guarded holds fields tested by an
+// enclosing if; inScope is false inside with/range where dot is rebound.
+func (s fieldSet) walk(node parse.Node, guarded map[string]bool, inScope bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			s.walk(child, guarded, inScope)
+		}
+	case *parse.ActionNode:
+		for _, field := range pipeFields(n.Pipe, inScope) {
+			if guarded[field] {
+				s.optional[field] = true
+			} else {
+				s.required[field] = true
+			}
+		}
+	case *parse.TemplateNode:
+		for _, field := range pipeFields(n.Pipe, inScope) {
+			s.required[field] = true
+		}
+	case *parse.IfNode:
+		s.branch(&n.BranchNode, guarded, inScope, inScope)
+	case *parse.WithNode:
+		s.branch(&n.BranchNode, guarded, inScope, false)
+	case *parse.RangeNode:
+		s.branch(&n.BranchNode, guarded, inScope, false)
+	}
+}
+
+func (s fieldSet) branch(n *parse.BranchNode, guarded map[string]bool, inScope, bodyInScope bool) {
+	inner := map[string]bool{}
+	for field := range guarded {
+		inner[field] = true
+	}
+	for _, field := range pipeFields(n.Pipe, inScope) {
+		s.optional[field] = true
+		inner[field] = true
+	}
+	s.walk(n.List, inner, bodyInScope)
+	if n.ElseList != nil {
+		s.walk(n.ElseList, guarded, inScope)
+	}
+}
+
+// pipeFields returns the top-level fields read by a pipeline: .name while
+// dot is the template data, and $.name anywhere
+func pipeFields(pipe *parse.PipeNode, inScope bool) []string {
+	if pipe == nil {
+		return nil
+	}
+	var fields []string
+	var visit func(parse.Node)
+	visit = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.FieldNode:
+			if inScope && len(n.Ident) > 0 {
+				fields = append(fields, n.Ident[0])
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				fields = append(fields, n.Ident[1])
+			}
+		case *parse.ChainNode:
+			visit(n.Node)
+		case *parse.PipeNode:
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					visit(arg)
+				}
+			}
+		}
+	}
+	visit(pipe)
+	return fields
+}
@@ -0,0 +1,187 @@
+package smtp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func mapFS(files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func TestVariables(t *testing.T) {
+	registry := NewRegistry(mapFS(map[string]string{
+		"notice.mn.subject": `{{.title}} {{if .urgent}}!{{end}}`,
+		"notice.mn.html": `{{.name}}
+{{if .comment}}<p>{{.comment}} {{.leader}}</p>{{else}}<p>{{.fallback}}</p>{{end}}
+{{with .team}}{{.Name}} {{$.team_mail}}{{end}}
+{{range .days}}{{.Date}} {{$.hours}}{{end}}
+{{if and .start .end}}{{.start}}-{{.end}}{{end}}
+{{printf "%s/%s" .base .path}} {{.employee.Email}} {{date .start_date}}
+{{define "row"}}<td>{{.}}</td>{{end}}{{template "row" .cell}}`,
+		"notice.mn.txt": `{{.name}} {{.plain_only}}`,
+	}), "", "mn")
+
+	vars, err := registry.Variables("notice", "mn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Variables{
+		Required: []string{
+			"base", "cell", "employee", "fallback", "hours", "leader", "name",
+			"path", "plain_only", "start_date", "team_mail", "title",
+		},
+		// Зөвхөн нөхцөлд ордог эсвэл өөрийн нөхцөл дотор хэвлэгддэг
+		Optional: []string{"comment", "days", "end", "start", "team", "urgent"},
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("Variables =\n %v\nwant\n %v", vars, want)
+	}
+}
+
+func TestRenderMissingVariables(t *testing.T) {
+	registry := NewRegistry(mapFS(map[string]string{
+		"notice.mn.subject": "  Чөлөө:\n {{.name}}  ",
+		"notice.mn.html":    `<b>{{.name}}</b>{{if .comment}} {{.comment}}{{end}} {{.leader}}`,
+		"notice.mn.txt":     `{{.name}} / {{.leader}}`,
+		"bare.mn.html":      `<p>{{.name}}</p>`,
+	}), "", "mn")
+	data := map[string]interface{}{"name": "Бат <Б>", "leader": nil}
+
+	rendered, err := registry.Render("notice", "mn", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Subject != "Чөлөө: Бат <Б>" {
+		t.Errorf("Subject = %q", rendered.Subject)
+	}
+	if rendered.HTML != "<b>Бат &lt;Б&gt;</b> " || rendered.Text != "Бат <Б> / " {
+		t.Errorf("HTML = %q, Text = %q", rendered.HTML, rendered.Text)
+	}
+	if !reflect.DeepEqual(rendered.Missing, []string{"leader"}) {
+		t.Errorf("Missing = %v, want [leader]", rendered.Missing)
+	}
+	if strings.Contains(rendered.HTML+rendered.Text, "no value") {
+		t.Errorf("missing variable rendered as <no value>")
+	}
+
+	preview, err := registry.Preview("notice", "mn", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.HTML != "<b>[[name]]</b> [[leader]]" || !reflect.DeepEqual(preview.Missing, []string{"leader", "name"}) {
+		t.Errorf("Preview HTML = %q, Missing = %v", preview.HTML, preview.Missing)
+	}
+
+	// Гарчиггүй загвар нэрээ гарчиг болгоно
+	bare, err := registry.Render("bare", "", map[string]interface{}{"name": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bare.Subject != "bare" || bare.Text != "" || bare.Language != "mn" {
+		t.Errorf("bare = %+v", bare)
+	}
+}
+
+func TestRegistryLanguageFallback(t *testing.T) {
+	embedded := mapFS(map[string]string{
+		"approved.mn.html": "Зөвшөөрлөө",
+		"approved.en.html": "Approved",
+		"rejected.mn.html": "Татгалзлаа",
+		"only.en.html":     "English only",
+	})
+	tests := []struct {
+		registry *Registry
+		name     string
+		lang     string
+		wantLang string
+		wantHTML string
+	}{
+		{NewRegistry(embedded, "", "mn"), "approved", "en", "en", "Approved"},
+		{NewRegistry(embedded, "", "mn"), "approved", "mn", "mn", "Зөвшөөрлөө"},
+		{NewRegistry(embedded, "", "mn"), "approved", "", "mn", "Зөвшөөрлөө"},
+		// en хувилбаргүй тул үндсэн хэл рүү буцна
+		{NewRegistry(embedded, "", "mn"), "rejected", "en", "mn", "Татгалзлаа"},
+		{NewRegistry(embedded, "", "mn"), "rejected", "fr", "mn", "Татгалзлаа"},
+		{NewRegistry(embedded, "", "en"), "approved", "", "en", "Approved"},
+		{NewRegistry(embedded, "", ""), "approved", "", "mn", "Зөвшөөрлөө"},
+		{NewRegistry(embedded, "", "en"), "only", "mn", "en", "English only"},
+	}
+	for _, tt := range tests {
+		rendered, err := tt.registry.Render(tt.name, tt.lang, nil)
+		if err != nil {
+			t.Errorf("Render(%s, %q) with default %s: %v", tt.name, tt.lang, tt.registry.DefaultLanguage(), err)
+			continue
+		}
+		if rendered.Language != tt.wantLang || rendered.HTML != tt.wantHTML {
+			t.Errorf("Render(%s, %q) with default %s = %s %q, want %s %q", tt.name, tt.lang,
+				tt.registry.DefaultLanguage(), rendered.Language, rendered.HTML, tt.wantLang, tt.wantHTML)
+		}
+	}
+
+	for _, tt := range []struct{ name, lang, def string }{
+		{"missing", "mn", "mn"},
+		{"only", "mn", "mn"},
+	} {
+		if _, err := NewRegistry(embedded, "", tt.def).Render(tt.name, tt.lang, nil); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Render(%s, %s) = %v, want ErrTemplateNotFound", tt.name, tt.lang, err)
+		}
+	}
+}
+
+func TestRegistryOverride(t *testing.T) {
+	embedded := mapFS(map[string]string{
+		"approved.mn.subject": "Суулгасан гарчиг",
+		"approved.mn.html":    "embedded {{.name}}",
+		"approved.mn.txt":     "embedded text",
+	})
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"approved.mn.html": "override {{.name}} {{.leader}}",
+		"approved.en.html": "override english",
+		"custom.mn.html":   "custom",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry := NewRegistry(embedded, dir, "mn")
+
+	rendered, err := registry.Render("approved", "mn", map[string]interface{}{"name": "Бат"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Override-д байгаа хэсэг нь давамгайлж, байхгүй хэсэг нь суулгасанаас уншигдана
+	if rendered.HTML != "override Бат " || rendered.Text != "embedded text" || rendered.Subject != "Суулгасан гарчиг" {
+		t.Errorf("Render = %+v", rendered)
+	}
+	if !reflect.DeepEqual(rendered.Missing, []string{"leader"}) {
+		t.Errorf("Missing = %v, variables must come from the override", rendered.Missing)
+	}
+	if rendered, err := registry.Render("approved", "en", nil); err != nil || rendered.HTML != "override english" {
+		t.Errorf("Render(en) = %+v, %v", rendered, err)
+	}
+
+	// Override файлыг засахад дахин эхлүүлэх шаардлагагүй
+	if err := os.WriteFile(filepath.Join(dir, "approved.mn.html"), []byte("edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if rendered, err := registry.Render("approved", "mn", nil); err != nil || rendered.HTML != "edited" {
+		t.Errorf("after edit Render = %+v, %v", rendered, err)
+	}
+
+	names := registry.Names()
+	want := map[string][]string{"approved": {"en", "mn"}, "custom": {"mn"}}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Names = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Memory хадгалах backend, тест болон локал хөгжүүлэлтэд
+type Memory struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+	baseURL string
+}
+
+func NewMemory(baseURL string) *Memory {
+	return &Memory{objects: map[string][]byte{}, baseURL: baseURL}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, content []byte, contentType string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = append([]byte(nil), content...)
+	return nil
+}
+
+func (m *Memory) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	content, ok := m.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *Memory) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return signedFileURL(m.baseURL, key, expires), nil
+}
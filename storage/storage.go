@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mcp-server/secrets"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrNotFound         = errors.New("storage: object not found")
+	ErrChecksumMismatch = errors.New("storage: checksum mismatch")
+	ErrInvalidSignature = errors.New("storage: invalid or expired signature")
+)
+
+// Storage файлын агуулга хадгалах backend
+type Storage interface {
+	Put(ctx context.Context, key string, content []byte, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that allows downloading key until it expires
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Object хадгалагдсан файлын мэдээлэл
+type Object struct {
+	Key      string // Backend дахь нэр, File.PhysicalPath-д хадгална
+	Name     string // Checksum + өргөтгөл, File.FileName-д хадгална
+	Checksum string // SHA-256 hex
+	Size     int64
+}
+
+var Default Storage
+
+// CreateClient initialize the storage backend selected by STORAGE_BACKEND
+func CreateClient() (Storage, error) {
+	var (
+		s   Storage
+		err error
+	)
+	switch backend := viper.GetString("STORAGE_BACKEND"); backend {
+	case "", "local":
+		s = NewLocal(Root(), publicBaseURL())
+	case "s3":
+		s, err = NewS3(S3Config{
+			Endpoint:  viper.GetString("S3_ENDPOINT"),
+			Region:    viper.GetString("S3_REGION"),
+			Bucket:    viper.GetString("S3_BUCKET"),
+			AccessKey: viper.GetString("S3_ACCESS_KEY"),
+			SecretKey: secrets.Get("S3_SECRET_KEY"),
+			PathStyle: viper.GetBool("S3_PATH_STYLE"),
+		})
+	case "memory":
+		s = NewMemory(publicBaseURL())
+	default:
+		err = fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	Default = s
+	return s, nil
+}
+
+// Root returns the local storage directory
+func Root() string {
+	if root := viper.GetString("FILE_STORAGE_ROOT"); root != "" {
+		return root
+	}
+	return "uploads"
+}
+
+func publicBaseURL() string {
+	if base := viper.GetString("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// Key returns the content-addressed key for a checksum: "ab/cd/abcd...ext"
+func Key(checksum, ext string) string {
+	return path.Join(checksum[:2], checksum[2:4], checksum+ext)
+}
+
+// ChecksumFromKey returns the checksum a content-addressed key was built
+// from, or "" for keys created before content addressing.
+func ChecksumFromKey(key string) string {
+	base := path.Base(key)
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	if len(base) != sha256.Size*2 || !strings.HasPrefix(key, base[:2]+"/"+base[2:4]+"/") {
+		return ""
+	}
+	if _, err := hex.DecodeString(base); err != nil {
+		return ""
+	}
+	return base
+}
+
+// Checksum returns the SHA-256 hex of content
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Save stores content under its SHA-256 key. Identical content is stored once.
+func Save(ctx context.Context, content []byte, ext, contentType string) (Object, error) {
+	checksum := Checksum(content)
+	object := Object{
+		Key:      Key(checksum, ext),
+		Name:     checksum + ext,
+		Checksum: checksum,
+		Size:     int64(len(content)),
+	}
+	if err := Default.Put(ctx, object.Key, content, contentType); err != nil {
+		return Object{}, err
+	}
+	return object, nil
+}
+
+// ReadAll returns the content of key after checking it against the checksum
+// in a content-addressed key. The whole object is read before anything is
+// returned so a corrupted file is never served; ErrChecksumMismatch then.
+func ReadAll(ctx context.Context, key string) ([]byte, error) {
+	rc, err := Default.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	if checksum := ChecksumFromKey(key); checksum != "" && Checksum(content) != checksum {
+		return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, key)
+	}
+	return content, nil
+}
+
+// SignedURL returns a download URL for key valid for expires
+func SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return Default.SignedURL(ctx, key, expires)
+}
+
+// signingKey нь local/memory backend-ийн татах холбоосыг гарын үсэглэнэ,
+// STORAGE_SIGNING_KEY эсвэл CIPHER_KEY-ээс гаргасан тусдаа түлхүүр
+func signingKey() []byte {
+	return secrets.SigningKey("STORAGE_SIGNING_KEY", "mcp-server storage url")
+}
+
+func signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, signingKey())
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignQuery returns expires and signature query values for key, checked by
+// VerifySignedURL. Routes other than /files sign their own keys with it.
+func SignQuery(key string, expires time.Duration) url.Values {
+	exp := time.Now().Add(expires).Unix()
+	return url.Values{
+		"expires":   {strconv.FormatInt(exp, 10)},
+		"signature": {signature(key, exp)},
+	}
+}
+
+// signedFileURL builds baseURL/files/<key>?expires=..&signature=.. served by this server
+func signedFileURL(baseURL, key string, expires time.Duration) string {
+	return baseURL + "/files/" + key + "?" + SignQuery(key, expires).Encode()
+}
+
+// VerifySignedURL checks the query of a URL produced by SignedURL for local
+// and memory backends.
+func VerifySignedURL(key string, query url.Values) error {
+	exp, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature(key, exp)), []byte(query.Get("signature"))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useStorage makes s the Default backend for the test
+func useStorage(t *testing.T, s Storage) {
+	t.Helper()
+	prev := Default
+	Default = s
+	t.Cleanup(func() { Default = prev })
+}
+
+// helloSum is the SHA-256 of "hello"
+const helloSum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestKey(t *testing.T) {
+	if got := Key(helloSum, ".txt"); got != "2c/f2/"+helloSum+".txt" {
+		t.Errorf("Key = %s", got)
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"2c/f2/" + helloSum + ".txt", helloSum},
+		{"2c/f2/" + helloSum, helloSum},
+		{"2c/f2/" + helloSum + ".tar.gz", helloSum},
+		// Хуучин, агуулгаар нэрлээгүй замууд
+		{"uploads/report.pdf", ""},
+		{"ab/cd/" + helloSum + ".txt", ""},
+		{"2c/f2/" + strings.Repeat("z", 64) + ".txt", ""},
+		{"2c/f2/2cf2.txt", ""},
+	}
+	for _, tt := range tests {
+		if got := ChecksumFromKey(tt.key); got != tt.want {
+			t.Errorf("ChecksumFromKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSaveReadAll(t *testing.T) {
+	memory := NewMemory("http://files.test")
+	useStorage(t, memory)
+	ctx := context.Background()
+
+	object, err := Save(ctx, []byte("hello"), ".txt", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Checksum != helloSum || object.Key != Key(helloSum, ".txt") || object.Name != helloSum+".txt" || object.Size != 5 {
+		t.Errorf("Save = %+v", object)
+	}
+	if got, err := ReadAll(ctx, object.Key); err != nil || string(got) != "hello" {
+		t.Errorf("ReadAll = %q, %v", got, err)
+	}
+
+	memory.Put(ctx, object.Key, []byte("jello"), "text/plain")
+	if got, err := ReadAll(ctx, object.Key); !errors.Is(err, ErrChecksumMismatch) || got != nil {
+		t.Errorf("ReadAll of a modified object = %q, %v, want ErrChecksumMismatch", got, err)
+	}
+	if _, err := ReadAll(ctx, "2c/f2/missing.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReadAll(missing) = %v, want ErrNotFound", err)
+	}
+
+	// Агуулгаар нэрлээгүй түлхүүрийг шалгахгүй
+	memory.Put(ctx, "legacy/report.txt", []byte("anything"), "text/plain")
+	if got, err := ReadAll(ctx, "legacy/report.txt"); err != nil || string(got) != "anything" {
+		t.Errorf("ReadAll(legacy) = %q, %v", got, err)
+	}
+}
+
+func TestLocalPutRepairsCorruptedObject(t *testing.T) {
+	root := t.TempDir()
+	local := NewLocal(root, "http://files.test")
+	useStorage(t, local)
+	ctx := context.Background()
+
+	object, err := Save(ctx, []byte("hello"), ".txt", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(root, filepath.FromSlash(object.Key))
+	if got, _ := os.ReadFile(p); string(got) != "hello" {
+		t.Fatalf("file = %q", got)
+	}
+
+	// Ижил агуулгыг дахин бичихгүй
+	stat, _ := os.Stat(p)
+	past := stat.ModTime().Add(-time.Hour)
+	os.Chtimes(p, past, past)
+	if _, err := Save(ctx, []byte("hello"), ".txt", "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if stat, _ := os.Stat(p); !stat.ModTime().Equal(past) {
+		t.Errorf("an intact object was rewritten")
+	}
+
+	if err := os.WriteFile(p, []byte("hellO"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadAll(ctx, object.Key); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("ReadAll of a corrupted file = %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := Save(ctx, []byte("hello"), ".txt", "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadAll(ctx, object.Key); err != nil || string(got) != "hello" {
+		t.Errorf("after re-upload ReadAll = %q, %v", got, err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(p), ".upload-*")); len(leftovers) != 0 {
+		t.Errorf("temporary files left: %v", leftovers)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	local := NewLocal(t.TempDir(), "http://files.test")
+	ctx := context.Background()
+	for _, key := range []string{"../secret", "/etc/passwd", "a/../../b", ".."} {
+		if err := local.Put(ctx, key, []byte("x"), ""); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := local.Open(ctx, key); err == nil {
+			t.Errorf("Open(%q) succeeded", key)
+		}
+	}
+	if _, err := local.Open(ctx, "2c/f2/missing.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	t.Setenv("STORAGE_SIGNING_KEY", "test-signing-key")
+	key := Key(helloSum, ".txt")
+
+	signed, err := NewLocal(t.TempDir(), "http://files.test").SignedURL(context.Background(), key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "files.test" || u.Path != "/files/"+key {
+		t.Errorf("SignedURL = %s", signed)
+	}
+	if err := VerifySignedURL(key, u.Query()); err != nil {
+		t.Errorf("VerifySignedURL: %v", err)
+	}
+
+	tamper := func(name, value string) url.Values {
+		q := url.Values{}
+		for k, v := range u.Query() {
+			q[k] = append([]string(nil), v...)
+		}
+		if value == "" {
+			q.Del(name)
+		} else {
+			q.Set(name, value)
+		}
+		return q
+	}
+	future := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name  string
+		key   string
+		query url.Values
+	}{
+		{"other key", Key(helloSum, ".pdf"), u.Query()},
+		{"extended expiry", key, tamper("expires", strconv.FormatInt(future, 10))},
+		{"changed signature", key, tamper("signature", strings.Repeat("0", 64))},
+		{"missing signature", key, tamper("signature", "")},
+		{"missing expiry", key, tamper("expires", "")},
+		{"bad expiry", key, tamper("expires", "soon")},
+		{"expired", key, SignQuery(key, -time.Second)},
+	}
+	for _, tt := range tests {
+		if err := VerifySignedURL(tt.key, tt.query); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: VerifySignedURL = %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+
+	// Өөр түлхүүрээр гарын үсэг зурсан холбоос хүчингүй
+	t.Setenv("STORAGE_SIGNING_KEY", "rotated-key")
+	if err := VerifySignedURL(key, u.Query()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifySignedURL after key rotation = %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mcp-server/auth"
+	"mcp-server/database"
+	"mcp-server/dates"
+	"mcp-server/outbox"
+	"mcp-server/secrets"
+	"mcp-server/telegram"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Telegram мессежийн загварууд: хэл -> имэйлийн загварын нэр -> текст
+var telegramTemplates = map[string]map[string]*template.Template{
+	"en": {
+		templateAbsenceRequest: telegramTemplate(`Absence request #{{.absence_id}}
+Employee: {{.employee_name}}
+Start: {{datetime .start_date}}
+End: {{datetime .end_date}}
+Reason: {{.reason}}
+Hours: {{.in_active_hours}}{{if .description}}
+Description: {{.description}}{{end}}`),
+		templateAbsenceApproved: telegramTemplate(`Your absence request #{{.absence_id}} was approved by {{.leader_name}}.
+{{datetime .start_date}} - {{datetime .end_date}}{{if .comment}}
+Comment: {{.comment}}{{end}}`),
+		templateAbsenceRejected: telegramTemplate(`Your absence request #{{.absence_id}} was rejected by {{.leader_name}}.
+{{datetime .start_date}} - {{datetime .end_date}}{{if .comment}}
+Comment: {{.comment}}{{end}}`),
+	},
+	"mn": {
+		templateAbsenceRequest: telegramTemplate(`Чөлөөний хүсэлт #{{.absence_id}}
+Ажилтан: {{.employee_name}}
+Эхлэх: {{datetime .start_date}}
+Дуусах: {{datetime .end_date}}
+Шалтгаан: {{.reason}}
+Цаг: {{.in_active_hours}}{{if .description}}
+Тайлбар: {{.description}}{{end}}`),
+		templateAbsenceApproved: telegramTemplate(`Таны чөлөөний хүсэлт #{{.absence_id}}-ийг {{.leader_name}} зөвшөөрлөө.
+{{datetime .start_date}} - {{datetime .end_date}}{{if .comment}}
+Тайлбар: {{.comment}}{{end}}`),
+		templateAbsenceRejected: telegramTemplate(`Таны чөлөөний хүсэлт #{{.absence_id}}-ийг {{.leader_name}} татгалзлаа.
+{{datetime .start_date}} - {{datetime .end_date}}{{if .comment}}
+Тайлбар: {{.comment}}{{end}}`),
+	},
+}
+
+var telegramButtons = map[string][2]string{
+	"en": {"Approve", "Reject"},
+	"mn": {"Зөвшөөрөх", "Татгалзах"},
+}
+
+func telegramTemplate(text string) *template.Template {
+	return template.Must(template.New("telegram").Funcs(template.FuncMap{"datetime": telegramDateTime}).Parse(text))
+}
+
+func telegramDateTime(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return dates.FormatDateTime(t)
+	}
+	return fmt.Sprint(v)
+}
+
+func telegramLanguage(tx *gorm.DB, user *database.User) string {
+	lang := recipientLanguage(tx, user)
+	if lang == "" {
+		lang = viper.GetString("MAIL_DEFAULT_LANGUAGE")
+	}
+	if _, ok := telegramTemplates[lang]; !ok {
+		lang = "mn"
+	}
+	return lang
+}
+
+// queueTelegram writes a Telegram message to the outbox inside tx when the
+// bot is configured and the user has a TelegramChannel. The request message
+// to the leader gets approve/reject buttons only when it goes to a private
+// chat; in a group or channel anyone could press them.
+func queueTelegram(tx *gorm.DB, absence *database.Absence, user *database.User, name string, data map[string]interface{}) error {
+	if telegram.Default == nil || user == nil || strings.TrimSpace(user.TelegramChannel) == "" {
+		return nil
+	}
+	lang := telegramLanguage(tx, user)
+	tmpl, ok := telegramTemplates[lang][name]
+	if !ok {
+		return nil
+	}
+	// Шалтгааны нэрийг Telegram-ийн хэлээр
+	values := map[string]interface{}{}
+	for k, v := range data {
+		values[k] = v
+	}
+	values["reason"] = reasonName(absence.Reason, lang)
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, values); err != nil {
+		fmt.Println("Telegram", name, "skipped, render failed", err)
+		return nil
+	}
+
+	msg := &telegram.Message{ChatID: strings.TrimSpace(user.TelegramChannel), Text: text.String()}
+	if name == templateAbsenceRequest && telegramPrivateChat(msg.ChatID) {
+		labels := telegramButtons[lang]
+		msg.ReplyMarkup = &telegram.InlineKeyboardMarkup{InlineKeyboard: [][]telegram.InlineKeyboardButton{{
+			{Text: labels[0], Data: fmt.Sprintf("%s:%d", AbsenceStatusApproved, absence.ID)},
+			{Text: labels[1], Data: fmt.Sprintf("%s:%d", AbsenceStatusRejected, absence.ID)},
+		}}}
+	}
+	_, err := outbox.EnqueuePayload(tx, outbox.ChannelTelegram, name, msg.ChatID, "", msg, &absence.ID)
+	return err
+}
+
+// telegramPrivateChat reports whether channel is a private chat, whose ID is
+// the user's positive numeric ID; groups are negative and channels @name
+func telegramPrivateChat(channel string) bool {
+	id, err := strconv.ParseInt(channel, 10, 64)
+	return err == nil && id > 0
+}
+
+// telegramUser finds the active user whose TelegramChannel is the sender's
+// numeric user ID. A @username can be changed or taken over by someone else,
+// so it never identifies who pressed the button; a private chat's ID is the
+// user ID. An ID linked to two users is refused rather than guessed.
+func telegramUser(from telegram.User) (*database.User, error) {
+	var users []database.User
+	if err := database.DB.Where("telegram_channel = ?", strconv.FormatInt(from.ID, 10)).Limit(2).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	switch len(users) {
+	case 0:
+		return nil, NewToolError(http.StatusForbidden, "This Telegram account is not linked to a user")
+	case 1:
+	default:
+		fmt.Println("Telegram user", from.ID, "is linked to more than one user")
+		return nil, NewToolError(http.StatusConflict, "This Telegram account is linked to more than one user")
+	}
+	if !users[0].IsActive {
+		return nil, NewToolError(http.StatusForbidden, "Your account is not active")
+	}
+	return &users[0], nil
+}
+
+// decideFromTelegram runs DecideAbsence for a button press of "approved:<id>"
+// or "rejected:<id>" and returns the text to show
+func decideFromTelegram(cb *telegram.CallbackQuery) (string, error) {
+	status, id, _ := strings.Cut(cb.Data, ":")
+	absenceID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || (status != AbsenceStatusApproved && status != AbsenceStatusRejected) {
+		return "", NewToolError(http.StatusBadRequest, "Unknown action")
+	}
+	user, err := telegramUser(cb.From)
+	if err != nil {
+		return "", err
+	}
+	caller, err := NewCaller(&auth.Identity{
+		User:   user,
+		Method: auth.MethodTelegram,
+		Scopes: []string{auth.ScopeAbsenceApprove},
+	})
+	if err != nil {
+		return "", err
+	}
+	tool := "approve_absence"
+	if status == AbsenceStatusRejected {
+		tool = "reject_absence"
+	}
+	if !caller.CanUse(tool) {
+		return "", NewToolError(http.StatusForbidden, "Your role is not allowed to decide absences")
+	}
+	if _, err := DecideAbsence(caller, uint(absenceID), status, ""); err != nil {
+		return "", err
+	}
+	labels := labelsFor(user)
+	if status == AbsenceStatusRejected {
+		return labels.Rejected, nil
+	}
+	return labels.Approved, nil
+}
+
+// TelegramWebhookHandler POST /telegram/webhook receives button presses.
+// Telegram must send TELEGRAM_WEBHOOK_SECRET in X-Telegram-Bot-Api-Secret-Token,
+// set with `go run . set-telegram-webhook`.
+func TelegramWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	secret := secrets.Get("TELEGRAM_WEBHOOK_SECRET")
+	if telegram.Default == nil || secret == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !hmac.Equal([]byte(r.Header.Get("X-Telegram-Bot-Api-Secret-Token")), []byte(secret)) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var update telegram.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&update); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	// Telegram алдаатай хариуг дахин илгээдэг тул үргэлж 200 буцаана
+	w.WriteHeader(http.StatusOK)
+	cb := update.CallbackQuery
+	if cb == nil {
+		return
+	}
+
+	ctx := context.Background()
+	result, err := decideFromTelegram(cb)
+	if err != nil {
+		var toolErr *ToolError
+		if !errors.As(err, &toolErr) {
+			fmt.Println("Telegram decision failed", err)
+			toolErr = NewToolError(http.StatusInternalServerError, "Internal server error")
+		}
+		if err := telegram.Default.AnswerCallback(ctx, cb.ID, toolErr.Message); err != nil {
+			fmt.Println("Telegram answerCallbackQuery failed", err)
+		}
+		return
+	}
+	if err := telegram.Default.AnswerCallback(ctx, cb.ID, result); err != nil {
+		fmt.Println("Telegram answerCallbackQuery failed", err)
+	}
+	// Товчлуурыг арилгаж шийдвэрийг мессежид үлдээнэ
+	if cb.Message != nil {
+		text := strings.TrimSpace(cb.Message.Text + "\n\n" + result)
+		if err := telegram.Default.EditText(ctx, cb.Message.Chat.ID, cb.Message.MessageID, text); err != nil {
+			fmt.Println("Telegram editMessageText failed", err)
+		}
+	}
+}
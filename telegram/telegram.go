@@ -0,0 +1,196 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mcp-server/secrets"
+
+	"github.com/spf13/viper"
+)
+
+const defaultBaseURL = "https://api.telegram.org"
+
+// Default нь TELEGRAM_BOT_TOKEN тохируулсан үед үүснэ, nil бол Telegram идэвхгүй
+var Default *Client
+
+// Client нь Telegram Bot API-ийн хэрэгтэй хэсэг
+type Client struct {
+	BaseURL string // TELEGRAM_API_BASE_URL, тестэд локал stub
+	Token   string
+	HTTP    *http.Client
+}
+
+// InlineKeyboardButton товчлуур, Data нь webhook-д callback_query болж ирнэ
+type InlineKeyboardButton struct {
+	Text string `json:"text"`
+	Data string `json:"callback_data,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// InlineKeyboardMarkup мөр мөрөөр товчлуурууд
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// Message нь sendMessage-ийн бие, outbox-д JSON-оор хадгалагдана
+type Message struct {
+	ChatID      string                `json:"chat_id"` // Тоон ID эсвэл @channel
+	Text        string                `json:"text"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// Update нь webhook-оор ирэх шинэчлэл, зөвхөн callback_query ашиглана
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	CallbackQuery *CallbackQuery `json:"callback_query"`
+}
+
+type CallbackQuery struct {
+	ID      string       `json:"id"`
+	From    User         `json:"from"`
+	Message *SentMessage `json:"message"`
+	Data    string       `json:"data"`
+}
+
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type Chat struct {
+	ID int64 `json:"id"`
+}
+
+type SentMessage struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+// APIError is a response with ok=false
+type APIError struct {
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram: %d %s", e.Code, e.Description)
+}
+
+// Configure enables the client when the TELEGRAM_BOT_TOKEN secret is set
+func Configure() (*Client, error) {
+	token := secrets.Get("TELEGRAM_BOT_TOKEN")
+	if token == "" {
+		Default = nil
+		return nil, nil
+	}
+	base := viper.GetString("TELEGRAM_API_BASE_URL")
+	if base == "" {
+		base = defaultBaseURL
+	}
+	Default = &Client{
+		BaseURL: strings.TrimRight(base, "/"),
+		Token:   token,
+		HTTP:    &http.Client{Timeout: 15 * time.Second},
+	}
+	fmt.Println("Telegram notifications enabled via", Default.BaseURL)
+	return Default, nil
+}
+
+// call posts params as JSON to the Bot API method and decodes result into out
+func (c *Client) call(ctx context.Context, method string, params, out interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/bot"+c.Token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		// URL-д токен байгаа тул алдааны мессежээс хасна
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram %s: %s: %w", method, resp.Status, err)
+	}
+	if !envelope.OK {
+		return &APIError{Code: envelope.ErrorCode, Description: envelope.Description}
+	}
+	if out != nil {
+		return json.Unmarshal(envelope.Result, out)
+	}
+	return nil
+}
+
+// Send delivers msg with sendMessage
+func (c *Client) Send(ctx context.Context, msg *Message) (*SentMessage, error) {
+	var sent SentMessage
+	if err := c.call(ctx, "sendMessage", msg, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// AnswerCallback stops the button's loading state and shows text to the user
+func (c *Client) AnswerCallback(ctx context.Context, callbackID, text string) error {
+	return c.call(ctx, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": callbackID,
+		"text":              text,
+	}, nil)
+}
+
+// EditText replaces the text of a sent message and removes its buttons
+func (c *Client) EditText(ctx context.Context, chatID, messageID int64, text string) error {
+	return c.call(ctx, "editMessageText", map[string]interface{}{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"text":       text,
+	}, nil)
+}
+
+// SetWebhook points the bot at webhookURL; Telegram sends secret in the
+// X-Telegram-Bot-Api-Secret-Token header of every update
+func (c *Client) SetWebhook(ctx context.Context, webhookURL, secret string) error {
+	return c.call(ctx, "setWebhook", map[string]interface{}{
+		"url":             webhookURL,
+		"secret_token":    secret,
+		"allowed_updates": []string{"callback_query"},
+	}, nil)
+}
+
+// Deliver sends an outbox payload, a JSON encoded Message
+func Deliver(payload []byte) error {
+	if Default == nil {
+		return errors.New("telegram is not configured")
+	}
+	var msg Message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		return err
+	}
+	_, err := Default.Send(context.Background(), &msg)
+	return err
+}
@@ -0,0 +1,252 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const testToken = "123456:test-bot-token"
+
+// botCall is one request the stub received
+type botCall struct {
+	Method string
+	Params map[string]interface{}
+}
+
+// botStub answers Bot API requests for testToken. reply returns the raw
+// response per method; without it every call succeeds, sendMessage with a
+// sent message and the others with result true.
+type botStub struct {
+	mu    sync.Mutex
+	calls []botCall
+	reply func(method string) (status int, body string)
+}
+
+func (s *botStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != testToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"ok":false,"error_code":401,"description":"Unauthorized"}`)
+		return
+	}
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	var params map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.calls = append(s.calls, botCall{Method: method, Params: params})
+	s.mu.Unlock()
+
+	status, body := http.StatusOK, `{"ok":true,"result":true}`
+	if method == "sendMessage" {
+		body = `{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`
+	}
+	if s.reply != nil {
+		status, body = s.reply(method)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	io.WriteString(w, body)
+}
+
+func (s *botStub) Calls() []botCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]botCall(nil), s.calls...)
+}
+
+func newBotStub(t *testing.T) (*Client, *botStub) {
+	t.Helper()
+	stub := &botStub{}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return &Client{BaseURL: server.URL, Token: testToken, HTTP: server.Client()}, stub
+}
+
+func TestSend(t *testing.T) {
+	client, stub := newBotStub(t)
+	stub.reply = func(string) (int, string) {
+		return http.StatusOK, `{"ok":true,"result":{"message_id":77,"chat":{"id":123456789},"text":"Чөлөөний хүсэлт #5"}}`
+	}
+	sent, err := client.Send(context.Background(), &Message{
+		ChatID: "123456789",
+		Text:   "Чөлөөний хүсэлт #5",
+		ReplyMarkup: &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{{
+			{Text: "Зөвшөөрөх", Data: "approved:5"},
+			{Text: "Татгалзах", Data: "rejected:5"},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.MessageID != 77 || sent.Chat.ID != 123456789 || sent.Text != "Чөлөөний хүсэлт #5" {
+		t.Errorf("Send = %+v", sent)
+	}
+
+	calls := stub.Calls()
+	if len(calls) != 1 || calls[0].Method != "sendMessage" {
+		t.Fatalf("calls = %+v", calls)
+	}
+	params := calls[0].Params
+	if params["chat_id"] != "123456789" || params["text"] != "Чөлөөний хүсэлт #5" {
+		t.Errorf("params = %v", params)
+	}
+	buttons, _ := json.Marshal(params["reply_markup"])
+	if string(buttons) != `{"inline_keyboard":[[{"callback_data":"approved:5","text":"Зөвшөөрөх"},{"callback_data":"rejected:5","text":"Татгалзах"}]]}` {
+		t.Errorf("reply_markup = %s", buttons)
+	}
+
+	// Товчлуургүй мессежид reply_markup илгээхгүй
+	if _, err := client.Send(context.Background(), &Message{ChatID: "@hr_channel", Text: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stub.Calls()[1].Params["reply_markup"]; ok {
+		t.Errorf("plain message sent reply_markup: %v", stub.Calls()[1].Params)
+	}
+}
+
+func TestAnswerCallbackAndEditText(t *testing.T) {
+	client, stub := newBotStub(t)
+	ctx := context.Background()
+	if err := client.AnswerCallback(ctx, "cb-1", "Зөвшөөрлөө"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.EditText(ctx, 123456789, 77, "Чөлөөний хүсэлт #5\n\nЗөвшөөрлөө"); err != nil {
+		t.Fatal(err)
+	}
+	calls := stub.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("calls = %+v", calls)
+	}
+	if calls[0].Method != "answerCallbackQuery" || calls[0].Params["callback_query_id"] != "cb-1" || calls[0].Params["text"] != "Зөвшөөрлөө" {
+		t.Errorf("answerCallbackQuery = %+v", calls[0])
+	}
+	// JSON тоонууд float64 болж задарна
+	edit := calls[1]
+	if edit.Method != "editMessageText" || edit.Params["chat_id"] != float64(123456789) || edit.Params["message_id"] != float64(77) ||
+		edit.Params["text"] != "Чөлөөний хүсэлт #5\n\nЗөвшөөрлөө" {
+		t.Errorf("editMessageText = %+v", edit)
+	}
+	if _, ok := edit.Params["reply_markup"]; ok {
+		t.Errorf("editMessageText keeps the buttons: %v", edit.Params)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	client, stub := newBotStub(t)
+	ctx := context.Background()
+
+	stub.reply = func(string) (int, string) {
+		return http.StatusBadRequest, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`
+	}
+	err := client.AnswerCallback(ctx, "cb-1", "x")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 400 || apiErr.Description != "Bad Request: chat not found" {
+		t.Errorf("ok=false = %v, want APIError 400", err)
+	}
+
+	// 200 боловч ok=false
+	stub.reply = func(string) (int, string) {
+		return http.StatusOK, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`
+	}
+	if _, err := client.Send(ctx, &Message{ChatID: "1", Text: "x"}); !errors.As(err, &apiErr) || apiErr.Code != 403 {
+		t.Errorf("200 with ok=false = %v, want APIError 403", err)
+	}
+
+	stub.reply = func(string) (int, string) { return http.StatusBadGateway, "<html>502 Bad Gateway</html>" }
+	err = client.EditText(ctx, 1, 2, "x")
+	if err == nil || errors.As(err, &apiErr) || !strings.Contains(err.Error(), "editMessageText") || !strings.Contains(err.Error(), "502") {
+		t.Errorf("non-JSON response = %v", err)
+	}
+
+	stub.reply = func(string) (int, string) { return http.StatusOK, `{"ok":true,"result":"not a message"}` }
+	if _, err := client.Send(ctx, &Message{ChatID: "1", Text: "x"}); err == nil {
+		t.Error("Send accepted a result that is not a message")
+	}
+
+	wrongToken := &Client{BaseURL: client.BaseURL, Token: "654321:other", HTTP: client.HTTP}
+	if err := wrongToken.AnswerCallback(ctx, "cb", "x"); !errors.As(err, &apiErr) || apiErr.Code != 401 {
+		t.Errorf("wrong token = %v, want APIError 401", err)
+	}
+
+	// Сүлжээний алдаанд токен URL-тай хамт гарахгүй
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	down := &Client{BaseURL: closed.URL, Token: testToken, HTTP: &http.Client{}}
+	err = down.AnswerCallback(ctx, "cb", "x")
+	if err == nil || strings.Contains(err.Error(), testToken) || !strings.Contains(err.Error(), "answerCallbackQuery") {
+		t.Errorf("connection error = %v", err)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	prev := Default
+	t.Cleanup(func() { Default = prev })
+
+	Default = nil
+	if err := Deliver([]byte(`{"chat_id":"1","text":"x"}`)); err == nil {
+		t.Error("Deliver without a client succeeded")
+	}
+
+	client, stub := newBotStub(t)
+	Default = client
+	if err := Deliver([]byte(`{"chat_id":"123456789","text":"Сайн уу","reply_markup":{"inline_keyboard":[[{"text":"OK","callback_data":"approved:1"}]]}}`)); err != nil {
+		t.Fatal(err)
+	}
+	calls := stub.Calls()
+	if len(calls) != 1 || calls[0].Method != "sendMessage" || calls[0].Params["chat_id"] != "123456789" || calls[0].Params["text"] != "Сайн уу" {
+		t.Fatalf("calls = %+v", calls)
+	}
+	if calls[0].Params["reply_markup"] == nil {
+		t.Errorf("buttons were dropped: %v", calls[0].Params)
+	}
+
+	if err := Deliver([]byte(`not json`)); err == nil || len(stub.Calls()) != 1 {
+		t.Errorf("Deliver(invalid payload) = %v", err)
+	}
+	stub.reply = func(string) (int, string) {
+		return http.StatusTooManyRequests, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 5"}`
+	}
+	var apiErr *APIError
+	if err := Deliver([]byte(`{"chat_id":"1","text":"x"}`)); !errors.As(err, &apiErr) || apiErr.Code != 429 {
+		t.Errorf("Deliver = %v, want the API error so the outbox retries", err)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	prev := Default
+	t.Cleanup(func() {
+		Default = prev
+		viper.Set("TELEGRAM_API_BASE_URL", "")
+	})
+
+	t.Setenv("TELEGRAM_BOT_TOKEN", "")
+	Default = &Client{}
+	if client, err := Configure(); client != nil || err != nil || Default != nil {
+		t.Errorf("Configure without a token = %v, %v", client, err)
+	}
+
+	t.Setenv("TELEGRAM_BOT_TOKEN", testToken)
+	client, err := Configure()
+	if err != nil || client == nil || client.BaseURL != defaultBaseURL || client.Token != testToken || Default != client {
+		t.Errorf("Configure = %+v, %v", client, err)
+	}
+	viper.Set("TELEGRAM_API_BASE_URL", "http://localhost:8081/")
+	if client, _ := Configure(); client.BaseURL != "http://localhost:8081" {
+		t.Errorf("BaseURL = %s", client.BaseURL)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"mcp-server/database"
+	"mcp-server/telegram"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTelegramPrivateChat(t *testing.T) {
+	for channel, want := range map[string]bool{
+		"123456789":      true,
+		"-1001234567890": false, // Супергрупп
+		"-4242":          false,
+		"0":              false,
+		"@hr_channel":    false,
+		"12 34":          false,
+		"":               false,
+	} {
+		if got := telegramPrivateChat(channel); got != want {
+			t.Errorf("telegramPrivateChat(%q) = %v, want %v", channel, got, want)
+		}
+	}
+}
+
+// fakeUsers is a database/sql driver answering every query with the same
+// users table rows, enough for telegramUser without a Postgres server
+type fakeUsers struct {
+	mu      sync.Mutex
+	columns []string
+	rows    [][]driver.Value
+	err     error
+	queries []string
+	args    [][]driver.NamedValue
+}
+
+func (f *fakeUsers) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeUsers) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeUsers }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.queries = append(c.db.queries, query)
+	c.db.args = append(c.db.args, args)
+	if c.db.err != nil {
+		return nil, c.db.err
+	}
+	return &fakeRows{columns: c.db.columns, rows: c.db.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// useFakeUsers points database.DB at users (id, is_active, telegram_channel)
+func useFakeUsers(t *testing.T, users ...[]driver.Value) *fakeUsers {
+	t.Helper()
+	fake := &fakeUsers{columns: []string{"id", "is_active", "telegram_channel"}, rows: users}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(fake)}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = prev })
+	return fake
+}
+
+// botCall is one Bot API request the webhook made
+type botCall struct {
+	Method string
+	Params map[string]interface{}
+}
+
+// useTelegramBot makes telegram.Default a client of a Bot API stub and
+// returns the calls it received
+func useTelegramBot(t *testing.T) func() []botCall {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []botCall
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&params)
+		mu.Lock()
+		calls = append(calls, botCall{Method: r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], Params: params})
+		mu.Unlock()
+		io.WriteString(w, `{"ok":true,"result":true}`)
+	}))
+	t.Cleanup(server.Close)
+
+	prev := telegram.Default
+	telegram.Default = &telegram.Client{BaseURL: server.URL, Token: "123456:test", HTTP: server.Client()}
+	t.Cleanup(func() { telegram.Default = prev })
+	t.Setenv("TELEGRAM_WEBHOOK_SECRET", "webhook-secret")
+	return func() []botCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]botCall(nil), calls...)
+	}
+}
+
+func postUpdate(secret, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	}
+	w := httptest.NewRecorder()
+	TelegramWebhookHandler(w, req)
+	return w
+}
+
+func buttonPress(data string) string {
+	return `{"update_id":1,"callback_query":{"id":"cb-1","from":{"id":42,"username":"bat"},"data":"` + data +
+		`","message":{"message_id":77,"chat":{"id":42},"text":"Чөлөөний хүсэлт #5"}}}`
+}
+
+func TestTelegramWebhookSecret(t *testing.T) {
+	prev := telegram.Default
+	telegram.Default = nil
+	t.Setenv("TELEGRAM_WEBHOOK_SECRET", "webhook-secret")
+	if w := postUpdate("webhook-secret", buttonPress("approved:5")); w.Code != http.StatusNotFound {
+		t.Errorf("without a bot = %d, want 404", w.Code)
+	}
+	telegram.Default = prev
+
+	calls := useTelegramBot(t)
+	fake := useFakeUsers(t)
+	for name, secret := range map[string]string{"missing": "", "wrong": "webhook-secreT", "prefix": "webhook"} {
+		if w := postUpdate(secret, buttonPress("approved:5")); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s secret = %d, want 401", name, w.Code)
+		}
+	}
+	if w := postUpdate("webhook-secret", "{"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid JSON = %d, want 400", w.Code)
+	}
+	if w := postUpdate("webhook-secret", `{"update_id":2,"message":{"text":"hi"}}`); w.Code != http.StatusOK {
+		t.Errorf("update without a button press = %d, want 200", w.Code)
+	}
+	if len(calls()) != 0 || len(fake.queries) != 0 {
+		t.Errorf("rejected updates reached the bot %v or the database %v", calls(), fake.queries)
+	}
+
+	t.Setenv("TELEGRAM_WEBHOOK_SECRET", "")
+	if w := postUpdate("", buttonPress("approved:5")); w.Code != http.StatusNotFound {
+		t.Errorf("without TELEGRAM_WEBHOOK_SECRET = %d, want 404", w.Code)
+	}
+}
+
+// pressButton posts a button press and returns the answerCallbackQuery text;
+// a refused press must not edit the message
+func pressButton(t *testing.T, calls func() []botCall, data string) string {
+	t.Helper()
+	before := len(calls())
+	if w := postUpdate("webhook-secret", buttonPress(data)); w.Code != http.StatusOK {
+		t.Fatalf("%s: status %d, Telegram needs 200", data, w.Code)
+	}
+	made := calls()[before:]
+	if len(made) != 1 || made[0].Method != "answerCallbackQuery" || made[0].Params["callback_query_id"] != "cb-1" {
+		t.Fatalf("%s: calls = %+v, want a single answerCallbackQuery", data, made)
+	}
+	return made[0].Params["text"].(string)
+}
+
+func TestTelegramWebhookUnknownAction(t *testing.T) {
+	calls := useTelegramBot(t)
+	fake := useFakeUsers(t, []driver.Value{int64(1), true, "42"})
+	for _, data := range []string{"delete:5", "approve:5", "approved:", "approved:abc", "approved:-5", "rejected", ""} {
+		if text := pressButton(t, calls, data); text != "Unknown action" {
+			t.Errorf("%q answered %q", data, text)
+		}
+	}
+	if len(fake.queries) != 0 {
+		t.Errorf("unknown actions queried the database: %v", fake.queries)
+	}
+}
+
+func TestTelegramWebhookUnlinkedUser(t *testing.T) {
+	calls := useTelegramBot(t)
+	tests := []struct {
+		name  string
+		users [][]driver.Value
+		err   error
+		want  string
+	}{
+		{"not linked", nil, nil, "This Telegram account is not linked to a user"},
+		{"inactive", [][]driver.Value{{int64(7), false, "42"}}, nil, "Your account is not active"},
+		{"linked twice", [][]driver.Value{{int64(7), true, "42"}, {int64(8), true, "42"}}, nil, "This Telegram account is linked to more than one user"},
+		{"database error", nil, errors.New("connection reset"), "Internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := useFakeUsers(t, tt.users...)
+			fake.err = tt.err
+			if text := pressButton(t, calls, "approved:5"); text != tt.want {
+				t.Errorf("answered %q, want %q", text, tt.want)
+			}
+			// Хэрэглэгчийг @username биш зөвхөн тоон ID-аар хайна
+			if len(fake.queries) != 1 || !strings.Contains(fake.queries[0], "telegram_channel") || fake.args[0][0].Value != "42" {
+				t.Errorf("queries = %v %v", fake.queries, fake.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mcp-server/auth"
+	"mcp-server/database"
+	"net/http"
+)
+
+// Tool tools/list-д буцаах хэрэгслийн тодорхойлолт
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	InputSchema Schema `json:"inputSchema"`
+}
+
+// Schema JSON Schema-ийн объект
+type Schema map[string]interface{}
+
+func objectSchema(required []string, properties map[string]Schema) Schema {
+	if required == nil {
+		required = []string{}
+	}
+	return Schema{"type": "object", "properties": properties, "required": required}
+}
+
+func stringSchema(description string) Schema {
+	return Schema{"type": "string", "description": description}
+}
+
+// enumSchema omits "enum" when values is empty, an empty enum accepts nothing
+func enumSchema(description string, values []string) Schema {
+	schema := Schema{"type": "string", "description": description}
+	if len(values) > 0 {
+		schema["enum"] = values
+	}
+	return schema
+}
+
+func numberSchema(description string) Schema {
+	return Schema{"type": "number", "description": description}
+}
+
+func integerSchema(description string) Schema {
+	return Schema{"type": "integer", "description": description}
+}
+
+func booleanSchema(description string) Schema {
+	return Schema{"type": "boolean", "description": description}
+}
+
+// Хэрэгсэл бүрт шаардагдах OAuth scope, хоосон бол зөвхөн нэвтэрсэн байхад хангалттай
+var toolScopes = map[string]string{
+	"set_notification_language":   "",
+	"get_teams":                   auth.ScopeUsersRead,
+	"get_users":                   auth.ScopeUsersRead,
+	"get_user_profile":            auth.ScopeUsersRead,
+	"get_effective_permissions":   auth.ScopeUsersRead,
+	"list_absences":               auth.ScopeAbsenceRead,
+	"get_absence_report":          auth.ScopeAdmin,
+	"set_user_role":               auth.ScopeAdmin,
+	"reveal_registration_number":  auth.ScopeAdmin,
+	"list_outbox":                 auth.ScopeAdmin,
+	"retry_outbox_message":        auth.ScopeAdmin,
+	"preview_email":               auth.ScopeAdmin,
+	"create_absence_request":      auth.ScopeAbsenceWrite,
+	"approve_absence":             auth.ScopeAbsenceApprove,
+	"reject_absence":              auth.ScopeAbsenceApprove,
+	"cancel_absence":              auth.ScopeAbsenceWrite,
+	"get_time_intervals":          auth.ScopeAbsenceRead,
+	"upload_absence_attachment":   auth.ScopeAbsenceWrite,
+	"list_absence_attachments":    auth.ScopeAbsenceRead,
+	"download_absence_attachment": auth.ScopeAbsenceRead,
+	"list_absence_reasons":        auth.ScopeAbsenceRead,
+	"create_absence_reason":       auth.ScopeAdmin,
+	"update_absence_reason":       auth.ScopeAdmin,
+}
+
+var absenceReasonProperties = map[string]Schema{
+	"code":                   stringSchema("Unique reason code, e.g. sick"),
+	"name_en":                stringSchema("English name"),
+	"name_mn":                stringSchema("Mongolian name"),
+	"is_paid":                booleanSchema("Paid leave"),
+	"counts_against_balance": booleanSchema("Deducted from the vacation balance"),
+	"requires_attachment":    booleanSchema("An attachment (e.g. doctor's note) is required before approval"),
+	"max_days":               numberSchema("Maximum working days per request, 0 for unlimited"),
+	"notice_days":            integerSchema("Days of notice required before the start date"),
+}
+
+// ListTools returns the tools the caller may use, with enums filled from the database
+func ListTools(caller *Caller) []Tool {
+	tools := []Tool{}
+	for _, tool := range allTools() {
+		if caller.CanUse(tool.Name) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+func allTools() []Tool {
+	reasonCodes := []string{}
+	database.DB.Model(&database.AbsenceReason{}).Where("is_active = ?", true).Order("code").Pluck("code", &reasonCodes)
+
+	updateReasonProperties := map[string]Schema{"is_active": booleanSchema("Whether the reason can be used in new requests")}
+	for name, schema := range absenceReasonProperties {
+		updateReasonProperties[name] = schema
+	}
+
+	return []Tool{
+		{
+			Name:        "get_teams",
+			Description: "List teams with their leader. Personal details are shown according to the caller's privacy tier",
+			InputSchema: objectSchema(nil, map[string]Schema{
+				"include_members":   booleanSchema("Also return team members"),
+				"include_sensitive": booleanSchema("HR only: return full employee records, the access is audited"),
+			}),
+		},
+		{
+			Name:        "get_users",
+			Description: "List users. Colleagues see phone and birthday (month-day), others only the public directory; registration numbers are always masked",
+			InputSchema: objectSchema(nil, map[string]Schema{
+				"team_id":           integerSchema("Only users of this team"),
+				"include_sensitive": booleanSchema("HR only: return full employee records, the access is audited"),
+			}),
+		},
+		{
+			Name:        "set_notification_language",
+			Description: "Choose the language of the emails you receive",
+			InputSchema: objectSchema([]string{"language"}, map[string]Schema{
+				"language": enumSchema("Language", notificationLanguages),
+			}),
+		},
+		{
+			Name:        "get_effective_permissions",
+			Description: "Show the roles, allowed tools and data scope of the caller, or of another user for administrators",
+			InputSchema: objectSchema(nil, map[string]Schema{
+				"user_id": integerSchema("User ID, defaults to the caller"),
+				"email":   stringSchema("User email, used when user_id is not given"),
+			}),
+		},
+		{
+			Name:        "get_user_profile",
+			Description: "Get a user's profile with profile picture URLs in several sizes; bio and contact details follow the same privacy tiers as get_users",
+			InputSchema: objectSchema(nil, map[string]Schema{
+				"user_id": integerSchema("User ID"),
+				"email":   stringSchema("User email, used when user_id is not given"),
+			}),
+		},
+		{
+			Name:        "create_absence_request",
+			Description: "Create an absence request for the authenticated user. Dates accept ISO 8601, YYYY-MM-DD, YYYY-MM-DD HH:MM or relative expressions such as \"tomorrow afternoon\" or \"ирэх даваа\"; the resolved dates are returned.",
+			InputSchema: objectSchema([]string{"start_date", "reason", "description"}, map[string]Schema{
+				"user_email":  stringSchema("Employee email, must be the authenticated user's email when given"),
+				"start_date":  stringSchema("Start date or date-time"),
+				"end_date":    stringSchema("End date or date-time, defaults to start_date"),
+				"kind":        enumSchema("Absence kind, inferred from the dates when omitted", AbsenceKinds),
+				"reason":      enumSchema("Absence reason code", reasonCodes),
+				"description": stringSchema("Free text description"),
+			}),
+		},
+		{
+			Name:        "list_absences",
+			Description: "List absences visible to the caller: own, own team's or everyone's depending on role",
+			InputSchema: objectSchema(nil, map[string]Schema{
+				"status":      enumSchema("Filter by status", []string{"pending", "approved", "rejected", "cancelled"}),
+				"employee_id": integerSchema("Filter by employee"),
+				"from":        stringSchema("Only absences ending on or after this date"),
+				"to":          stringSchema("Only absences starting on or before this date"),
+			}),
+		},
+		{
+			Name:        "approve_absence",
+			Description: "Approve a pending absence request assigned to the authenticated leader",
+			InputSchema: objectSchema([]string{"absence_id"}, map[string]Schema{
+				"absence_id": integerSchema("Absence ID"),
+				"comment":    stringSchema("Comment for the employee"),
+			}),
+		},
+		{
+			Name:        "reject_absence",
+			Description: "Reject a pending absence request assigned to the authenticated leader",
+			InputSchema: objectSchema([]string{"absence_id"}, map[string]Schema{
+				"absence_id": integerSchema("Absence ID"),
+				"comment":    stringSchema("Comment for the employee"),
+			}),
+		},
+		{
+			Name:        "cancel_absence",
+			Description: "Cancel a pending or approved absence. Approved absences send a calendar cancellation to the employee and team.",
+			InputSchema: objectSchema([]string{"absence_id"}, map[string]Schema{
+				"absence_id": integerSchema("Absence ID"),
+				"comment":    stringSchema("Why the absence is cancelled"),
+			}),
+		},
+		{
+			Name:        "get_time_intervals",
+			Description: "List time intervals ending on or after the given date",
+			InputSchema: objectSchema([]string{"start_date"}, map[string]Schema{
+				"start_date": stringSchema("Date or relative expression"),
+			}),
+		},
+		{
+			Name:        "upload_absence_attachment",
+			Description: fmt.Sprintf("Attach a file (PDF, JPEG, PNG, GIF or WebP, at most %d bytes) to an absence request", attachmentMaxSize()),
+			InputSchema: objectSchema([]string{"absence_id", "file_name", "content_base64"}, map[string]Schema{
+				"absence_id":     integerSchema("Absence ID"),
+				"file_name":      stringSchema("Original file name"),
+				"content_base64": stringSchema("File content, base64 encoded"),
+			}),
+		},
+		{
+			Name:        "list_absence_attachments",
+			Description: "List files attached to an absence request",
+			InputSchema: objectSchema([]string{"absence_id"}, map[string]Schema{
+				"absence_id": integerSchema("Absence ID"),
+			}),
+		},
+		{
+			Name:        "download_absence_attachment",
+			Description: "Download an absence attachment as base64",
+			InputSchema: objectSchema([]string{"absence_id", "file_id"}, map[string]Schema{
+				"absence_id": integerSchema("Absence ID"),
+				"file_id":    integerSchema("File ID"),
+			}),
+		},
+		{
+			Name:        "list_absence_reasons",
+			Description: "List the absence reason catalogue with its policies",
+			InputSchema: objectSchema(nil, map[string]Schema{
+				"include_inactive": booleanSchema("Include deactivated reasons"),
+			}),
+		},
+		{
+			Name:        "get_absence_report",
+			Description: "Absence totals per employee and reason for a period (defaults to the current month, approved absences)",
+			InputSchema: objectSchema(nil, map[string]Schema{
+				"from":   stringSchema("Period start date"),
+				"to":     stringSchema("Period end date, inclusive"),
+				"status": enumSchema("Absence status", []string{"pending", "approved", "rejected", "cancelled"}),
+			}),
+		},
+		{
+			Name:        "set_user_role",
+			Description: "Change the role of another user whose current role you also hold, to a role you hold yourself; their connected clients receive notifications/tools/list_changed",
+			InputSchema: objectSchema([]string{"user_id", "role"}, map[string]Schema{
+				"user_id": integerSchema("User ID"),
+				"role":    stringSchema("Role name"),
+			}),
+		},
+		{
+			Name:        "reveal_registration_number",
+			Description: "HR only: decrypt a user's registration number. Every call is written to the audit log",
+			InputSchema: objectSchema([]string{"user_id", "reason"}, map[string]Schema{
+				"user_id": integerSchema("User ID"),
+				"reason":  stringSchema("Why the registration number is needed, stored in the audit log"),
+			}),
+		},
+		{
+			Name:        "list_outbox",
+			Description: "List queued, sent and dead-lettered notifications (email and Telegram), newest first",
+			InputSchema: objectSchema(nil, map[string]Schema{
+				"status":     enumSchema("Filter by status", []string{"pending", "sending", "sent", "dead"}),
+				"absence_id": integerSchema("Only emails about this absence"),
+				"limit":      integerSchema("Maximum number of messages, default 50"),
+			}),
+		},
+		{
+			Name:        "retry_outbox_message",
+			Description: "Queue a failed or dead-lettered email for an immediate new attempt",
+			InputSchema: objectSchema([]string{"id"}, map[string]Schema{
+				"id": integerSchema("Outbox message ID"),
+			}),
+		},
+		{
+			Name:        "preview_email",
+			Description: "Render an email template with sample or supplied data and list variables the data is missing",
+			InputSchema: objectSchema([]string{"template"}, map[string]Schema{
+				"template": enumSchema("Template name", emailTemplateNames()),
+				"language": enumSchema("Template language, defaults to MAIL_DEFAULT_LANGUAGE", notificationLanguages),
+				"subtitle": stringSchema("Optional subtitle shown in the subject and heading"),
+				"data":     {"type": "object", "description": "Template variables, merged over the template's sample data"},
+			}),
+		},
+		{
+			Name:        "create_absence_reason",
+			Description: "Add a reason to the absence reason catalogue",
+			InputSchema: objectSchema([]string{"code", "name_en", "name_mn"}, absenceReasonProperties),
+		},
+		{
+			Name:        "update_absence_reason",
+			Description: "Update or deactivate an absence reason by code",
+			InputSchema: objectSchema([]string{"code"}, updateReasonProperties),
+		},
+	}
+}
+
+// ToolError хэрэгслийн алдаа, HTTP статустай буцна
+type ToolError struct {
+	Status  int
+	Message string
+}
+
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+func NewToolError(status int, format string, args ...interface{}) *ToolError {
+	return &ToolError{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+func writeToolError(w http.ResponseWriter, err error) {
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) {
+		http.Error(w, toolErr.Message, toolErr.Status)
+		return
+	}
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	value, _ := args[name].(string)
+	return value
+}
+
+func requiredStringArg(args map[string]interface{}, name string) (string, error) {
+	value := stringArg(args, name)
+	if value == "" {
+		return "", NewToolError(http.StatusBadRequest, "%s is required", name)
+	}
+	return value, nil
+}
+
+func boolArg(args map[string]interface{}, name string) (bool, bool) {
+	value, ok := args[name].(bool)
+	return value, ok
+}
+
+func floatArg(args map[string]interface{}, name string) (float64, bool) {
+	value, ok := args[name].(float64)
+	return value, ok
+}
+
+func uintArg(args map[string]interface{}, name string) (uint, error) {
+	value, ok := floatArg(args, name)
+	if !ok || value <= 0 {
+		return 0, NewToolError(http.StatusBadRequest, "%s is required", name)
+	}
+	return uint(value), nil
+}